@@ -19,12 +19,13 @@ const (
 var fonts embed.FS
 
 func List() {
-	for _, v := range getAllFaceNames() {
+	for _, v := range Names() {
 		fmt.Println(v)
 	}
 }
 
-func getAllFaceNames() []string {
+// Names returns the names of the embedded fonts
+func Names() []string {
 	files, _ := fonts.ReadDir("fonts")
 	var names []string
 	for i := range files {
@@ -37,7 +38,7 @@ func EchoAll(text string) {
 	if text == "" {
 		text = defaultText
 	}
-	for _, v := range getAllFaceNames() {
+	for _, v := range Names() {
 		fmt.Printf("\nFont: %s\n", v)
 		Echo(text, v)
 	}
@@ -39,6 +39,14 @@ func (c *asciiArtCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&c.face, "face", "", "typeface of the ascii")
 }
 
+// FlagValues lists the values of -face for shell completion
+func (*asciiArtCmd) FlagValues(name string) []string {
+	if name == "face" {
+		return font.Names()
+	}
+	return nil
+}
+
 func (c *asciiArtCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
 	if c.list {
 		font.List()
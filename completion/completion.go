@@ -0,0 +1,200 @@
+package completion
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/subcommands"
+)
+
+// FlagValuer is implemented by commands whose flags take values from a known
+// set, such as font or language names
+type FlagValuer interface {
+	FlagValues(name string) []string
+}
+
+// ArgValuer is implemented by commands whose arguments come from a known set
+type ArgValuer interface {
+	ArgValues() []string
+}
+
+// Parent is implemented by commands with nested subcommands
+type Parent interface {
+	Subcommands() []subcommands.Command
+}
+
+var shells = map[string]func(w io.Writer, prog string, root *spec){
+	"bash": writeBash,
+	"zsh":  writeZsh,
+	"fish": writeFish,
+}
+
+type completionCmd struct {
+	prog string
+}
+
+// New returns a new completion command.
+func New() subcommands.Command {
+	return &completionCmd{}
+}
+
+func (*completionCmd) Name() string     { return "completion" }
+func (*completionCmd) Synopsis() string { return "Generate shell completion scripts." }
+func (*completionCmd) Usage() string {
+	return `completion [-prog name] bash|zsh|fish:
+	Print a completion script for the shell, generated from the registered commands.
+
+	bash: source <(cmd completion bash)
+	zsh:  cmd completion zsh > "${fpath[1]}/_cmd"
+	fish: cmd completion fish > ~/.config/fish/completions/cmd.fish
+`
+}
+
+func (c *completionCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.prog, "prog", filepath.Base(os.Args[0]), "name of the binary to complete")
+}
+
+// ArgValues lists the supported shells
+func (*completionCmd) ArgValues() []string {
+	names := make([]string, 0, len(shells))
+	for name := range shells {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *completionCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
+	write, ok := shells[f.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unsupported shell %q (available: %s)\n", f.Arg(0), strings.Join(c.ArgValues(), ", "))
+		return subcommands.ExitUsageError
+	}
+	write(os.Stdout, c.prog, rootSpec(subcommands.DefaultCommander, flag.CommandLine))
+	return subcommands.ExitSuccess
+}
+
+// spec describes a command for completion
+type spec struct {
+	path     string
+	name     string
+	synopsis string
+	flags    []flagSpec
+	args     []string
+	subs     []*spec
+}
+
+// flagSpec describes a flag for completion
+type flagSpec struct {
+	name   string
+	usage  string
+	isBool bool
+	values []string
+}
+
+// rootSpec describes the top-level flags and the registered commands
+func rootSpec(cdr *subcommands.Commander, top *flag.FlagSet) *spec {
+	root := &spec{flags: flagSpecs(top, nil)}
+	seen := map[string]bool{}
+	cdr.VisitCommands(func(_ *subcommands.CommandGroup, cmd subcommands.Command) {
+		if !seen[cmd.Name()] {
+			seen[cmd.Name()] = true
+			root.subs = append(root.subs, commandSpec("", cmd))
+		}
+	})
+	sort.Slice(root.subs, func(i, j int) bool { return root.subs[i].name < root.subs[j].name })
+	return root
+}
+
+// commandSpec describes cmd and its nested subcommands
+func commandSpec(parent string, cmd subcommands.Command) *spec {
+	s := &spec{path: strings.TrimPrefix(parent+"/"+cmd.Name(), "/"), name: cmd.Name(), synopsis: cmd.Synopsis()}
+
+	fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	cmd.SetFlags(fs)
+	valuer, _ := cmd.(FlagValuer)
+	s.flags = flagSpecs(fs, valuer)
+
+	if a, ok := cmd.(ArgValuer); ok {
+		s.args = a.ArgValues()
+	}
+	if p, ok := cmd.(Parent); ok {
+		for _, sub := range p.Subcommands() {
+			s.subs = append(s.subs, commandSpec(s.path, sub))
+		}
+	}
+	return s
+}
+
+// flagSpecs describes the flags of fs
+func flagSpecs(fs *flag.FlagSet, valuer FlagValuer) []flagSpec {
+	var specs []flagSpec
+	fs.VisitAll(func(f *flag.Flag) {
+		fl := flagSpec{name: f.Name, usage: f.Usage}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			fl.isBool = true
+		} else if valuer != nil {
+			fl.values = valuer.FlagValues(f.Name)
+		}
+		specs = append(specs, fl)
+	})
+	return specs
+}
+
+// walk calls fn for s and all nested commands
+func (s *spec) walk(fn func(*spec)) {
+	fn(s)
+	for _, sub := range s.subs {
+		sub.walk(fn)
+	}
+}
+
+// subNames returns the names of the nested commands
+func (s *spec) subNames() []string {
+	names := make([]string, 0, len(s.subs))
+	for _, sub := range s.subs {
+		names = append(names, sub.name)
+	}
+	return names
+}
+
+// valueFlags returns the names of the flags that take a value
+func (s *spec) valueFlags() []string {
+	var names []string
+	for _, f := range s.flags {
+		if !f.isBool {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// funcName turns the binary name into a shell identifier
+func funcName(prog string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, prog)
+}
+
+// quote quotes s for POSIX shells
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, which escapes quotes inside single quotes
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,106 @@
+package completion
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/subcommands"
+)
+
+type testCmd struct {
+	name string
+	subs []subcommands.Command
+}
+
+func (c *testCmd) Name() string     { return c.name }
+func (c *testCmd) Synopsis() string { return "test " + c.name }
+func (c *testCmd) Usage() string    { return c.name }
+func (c *testCmd) SetFlags(f *flag.FlagSet) {
+	f.Bool("verbose", false, "verbose output")
+	f.String("lang", "", "language")
+}
+func (c *testCmd) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	return subcommands.ExitSuccess
+}
+func (c *testCmd) FlagValues(name string) []string {
+	if name == "lang" {
+		return []string{"zh", "ja"}
+	}
+	return nil
+}
+func (c *testCmd) Subcommands() []subcommands.Command { return c.subs }
+
+func testSpec() *spec {
+	cdr := subcommands.NewCommander(flag.NewFlagSet("tool", flag.ContinueOnError), "tool")
+	cdr.Register(&testCmd{name: "translate", subs: []subcommands.Command{&testCmd{name: "serve"}}}, "")
+	cdr.Register(New(), "")
+	return rootSpec(cdr, flag.NewFlagSet("tool", flag.ContinueOnError))
+}
+
+func TestRootSpec(t *testing.T) {
+	root := testSpec()
+	if got := strings.Join(root.subNames(), " "); got != "completion translate" {
+		t.Fatalf("commands = %q", got)
+	}
+	translate := root.subs[1]
+	if translate.subs[0].path != "translate/serve" {
+		t.Errorf("nested path = %q", translate.subs[0].path)
+	}
+	if got := strings.Join(translate.valueFlags(), " "); got != "lang" {
+		t.Errorf("value flags = %q", got)
+	}
+	if got := strings.Join(root.subs[0].args, " "); got != "bash fish zsh" {
+		t.Errorf("completion args = %q", got)
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	var script bytes.Buffer
+	writeBash(&script, "tool", testSpec())
+
+	tests := []struct{ line, want string }{
+		{"tool tr", "translate"},
+		{"tool translate -l", "-lang"},
+		{"tool translate -lang j", "ja"},
+		{"tool translate -lang zh se", "serve"},
+		{"tool translate -verbose se", "serve"},
+		{"tool translate serve -v", "-verbose"},
+		{"tool completion f", "fish"},
+		{"tool translate text se", ""},
+	}
+	for _, tt := range tests {
+		words := strings.Fields(tt.line)
+		cmd := exec.Command(bash, "--norc", "-c", script.String()+`
+COMP_WORDS=("$@"); COMP_CWORD=$((${#COMP_WORDS[@]} - 1)); _tool; echo "${COMPREPLY[*]}"`, "bash")
+		cmd.Args = append(cmd.Args, words...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", tt.line, err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != tt.want {
+			t.Errorf("%q completes to %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestScripts(t *testing.T) {
+	root := testSpec()
+	for shell, write := range shells {
+		var b bytes.Buffer
+		write(&b, "my-tool", root)
+		out := b.String()
+		for _, want := range []string{"my_tool", "translate", "serve", "zh"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s script is missing %q", shell, want)
+			}
+		}
+	}
+}
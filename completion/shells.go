@@ -0,0 +1,288 @@
+package completion
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// caseFunc writes a shell function printing, for the command path in $1 (and
+// the flag name in $2 when byFlag is set), the lines returned by lines
+func caseFunc(w io.Writer, name string, root *spec, byFlag bool, lines func(s *spec, f *flagSpec) []string) {
+	subject := `"$1"`
+	if byFlag {
+		subject = `"$1:$2"`
+	}
+	fmt.Fprintf(w, "%s() {\n    case %s in\n", name, subject)
+	root.walk(func(s *spec) {
+		if !byFlag {
+			if out := lines(s, nil); len(out) > 0 {
+				fmt.Fprintf(w, "    %s) printf '%%s\\n' %s ;;\n", quote(s.path), quoteAll(out))
+			}
+			return
+		}
+		for i := range s.flags {
+			if out := lines(s, &s.flags[i]); len(out) > 0 {
+				fmt.Fprintf(w, "    %s) printf '%%s\\n' %s ;;\n", quote(s.path+":"+s.flags[i].name), quoteAll(out))
+			}
+		}
+	})
+	fmt.Fprint(w, "    esac\n}\n\n")
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quote(v)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// writeTables writes the functions describing the command tree, shared by
+// the bash and zsh scripts. With describe set, flags are printed as
+// name:description pairs and the commands are also listed that way.
+func writeTables(w io.Writer, fn string, root *spec, describe bool) {
+	caseFunc(w, fn+"_subs", root, false, func(s *spec, _ *flagSpec) []string { return s.subNames() })
+	if describe {
+		caseFunc(w, fn+"_commands", root, false, func(s *spec, _ *flagSpec) []string {
+			var out []string
+			for _, sub := range s.subs {
+				out = append(out, strings.ReplaceAll(sub.name, ":", `\:`)+":"+sub.synopsis)
+			}
+			return out
+		})
+	}
+	caseFunc(w, fn+"_args", root, false, func(s *spec, _ *flagSpec) []string { return s.args })
+	caseFunc(w, fn+"_flags", root, false, func(s *spec, _ *flagSpec) []string {
+		var out []string
+		for _, f := range s.flags {
+			if describe {
+				out = append(out, "-"+f.name+":"+f.usage)
+			} else {
+				out = append(out, "-"+f.name)
+			}
+		}
+		return out
+	})
+	caseFunc(w, fn+"_value_flags", root, false, func(s *spec, _ *flagSpec) []string { return s.valueFlags() })
+	caseFunc(w, fn+"_values", root, true, func(_ *spec, f *flagSpec) []string { return f.values })
+}
+
+// scanWords is the loop, shared by bash and zsh, that finds the command path
+// of the words before the cursor; flag values and "=" are skipped and the
+// first word that is not a subcommand ends the path
+const scanWords = `        w="${words[i]}"
+        if [[ $w == "=" ]]; then
+            skip=1
+            continue
+        fi
+        if ((skip)); then
+            skip=0
+            continue
+        fi
+        case "$w" in
+        -*=*) ;;
+        -*)
+            name="${w#-}"
+            name="${name#-}"
+            if printf '%%s\n' $(%[1]s_value_flags "$cmdpath") | grep -qxF -- "$name"; then
+                skip=1
+            fi
+            ;;
+        *)
+            if ((!stop)) && printf '%%s\n' $(%[1]s_subs "$cmdpath") | grep -qxF -- "$w"; then
+                cmdpath="${cmdpath:+$cmdpath/}$w"
+            else
+                stop=1
+            fi
+            ;;
+        esac
+`
+
+// writeBash writes a bash completion script
+func writeBash(w io.Writer, prog string, root *spec) {
+	fn := "_" + funcName(prog)
+	fmt.Fprintf(w, "# bash completion for %s, generated by \"%s completion bash\"\n\n", prog, prog)
+	writeTables(w, fn, root, false)
+	fmt.Fprintf(w, `%[1]s() {
+    local words=("${COMP_WORDS[@]}") cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmdpath="" stop=0 skip=0 i w name flag=""
+    for ((i = 1; i < COMP_CWORD; i++)); do
+%[2]s    done
+
+    # bash splits -flag=value into three words
+    if [[ $cur == "=" ]]; then
+        flag="$prev"
+        cur=""
+    elif [[ $prev == "=" ]]; then
+        flag="${COMP_WORDS[COMP_CWORD-2]}"
+    elif [[ $prev == -* ]]; then
+        flag="$prev"
+    fi
+    if [[ -n $flag ]]; then
+        name="${flag#-}"
+        name="${name#-}"
+        if printf '%%s\n' $(%[1]s_value_flags "$cmdpath") | grep -qxF -- "$name"; then
+            COMPREPLY=($(compgen -W "$(%[1]s_values "$cmdpath" "$name")" -- "$cur"))
+            return
+        fi
+    fi
+
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$(%[1]s_flags "$cmdpath")" -- "$cur"))
+    elif ((!stop)); then
+        COMPREPLY=($(compgen -W "$(%[1]s_subs "$cmdpath") $(%[1]s_args "$cmdpath")" -- "$cur"))
+    fi
+}
+
+complete -o default -F %[1]s %[3]s
+`, fn, fmt.Sprintf(scanWords, fn), prog)
+}
+
+// writeZsh writes a zsh completion script
+func writeZsh(w io.Writer, prog string, root *spec) {
+	fn := "_" + funcName(prog)
+	fmt.Fprintf(w, "#compdef %s\n\n# zsh completion for %s, generated by \"%s completion zsh\"\n\n", prog, prog, prog)
+	writeTables(w, fn, root, true)
+	fmt.Fprintf(w, `%[1]s() {
+    local cur="${words[CURRENT]}" prev="${words[CURRENT-1]}"
+    local cmdpath="" stop=0 skip=0 i w name
+    local -a items
+    for ((i = 2; i < CURRENT; i++)); do
+%[2]s    done
+
+    name=""
+    if [[ $cur == -*=* ]]; then
+        name="${cur%%%%=*}"
+        compset -P '*='
+    elif [[ $prev == -* && $prev != *=* ]]; then
+        name="$prev"
+    fi
+    name="${name#-}"
+    name="${name#-}"
+    if [[ -n $name ]] && printf '%%s\n' $(%[1]s_value_flags "$cmdpath") | grep -qxF -- "$name"; then
+        items=(${(f)"$(%[1]s_values "$cmdpath" "$name")"})
+        if ((${#items})); then
+            compadd -a items
+        else
+            _files
+        fi
+        return
+    fi
+
+    if [[ $cur == -* ]]; then
+        items=(${(f)"$(%[1]s_flags "$cmdpath")"})
+        _describe -t flags 'flag' items
+    elif ((!stop)); then
+        local -a args
+        items=(${(f)"$(%[1]s_commands "$cmdpath")"})
+        args=(${(f)"$(%[1]s_args "$cmdpath")"})
+        ((${#items})) && _describe -t commands 'command' items
+        ((${#args})) && compadd -a args
+        ((${#items} + ${#args})) || _files
+    else
+        _files
+    fi
+}
+
+if [[ "$funcstack[1]" = "%[1]s" ]]; then
+    %[1]s "$@"
+else
+    compdef %[1]s %[3]s
+fi
+`, fn, fmt.Sprintf(scanWords, fn), prog)
+}
+
+// writeFish writes a fish completion script
+func writeFish(w io.Writer, prog string, root *spec) {
+	fn := "__" + funcName(prog)
+	fmt.Fprintf(w, "# fish completion for %s, generated by \"%s completion fish\"\n\n", prog, prog)
+
+	// Tables used to find the command path of the words before the cursor
+	for _, table := range []struct {
+		name  string
+		lines func(s *spec) []string
+	}{
+		{"subs", (*spec).subNames},
+		{"value_flags", (*spec).valueFlags},
+	} {
+		fmt.Fprintf(w, "function %s_%s\n    switch $argv[1]\n", fn, table.name)
+		root.walk(func(s *spec) {
+			if out := table.lines(s); len(out) > 0 {
+				fmt.Fprintf(w, "        case %s\n            printf '%%s\\n' %s\n", fishQuote(s.path), fishQuoteAll(out))
+			}
+		})
+		fmt.Fprint(w, "    end\nend\n\n")
+	}
+
+	// The state is "<stop>:<path>", stop being 1 once a positional argument
+	// that is not a subcommand has been seen
+	fmt.Fprintf(w, `function %[1]s_state
+    set -l cmdpath ''
+    set -l stop 0
+    set -l skip 0
+    for w in (commandline -opc)[2..-1]
+        if test $skip = 1
+            set skip 0
+            continue
+        end
+        switch $w
+            case '-*=*'
+            case '-*'
+                if contains -- (string replace -r -- '^--?' '' $w) (%[1]s_value_flags $cmdpath)
+                    set skip 1
+                end
+            case '*'
+                if test $stop = 0; and contains -- $w (%[1]s_subs $cmdpath)
+                    if test -z "$cmdpath"
+                        set cmdpath $w
+                    else
+                        set cmdpath "$cmdpath/$w"
+                    end
+                else
+                    set stop 1
+                end
+        end
+    end
+    echo "$stop:$cmdpath"
+end
+
+# %[1]s_at PATH: the words before the cursor select the command PATH
+function %[1]s_at
+    string match -q -- "*:$argv[1]" (%[1]s_state)
+end
+
+# %[1]s_free PATH: as %[1]s_at, and no positional argument was given yet
+function %[1]s_free
+    test (%[1]s_state) = "0:$argv[1]"
+end
+
+`, fn)
+
+	root.walk(func(s *spec) {
+		for _, sub := range s.subs {
+			fmt.Fprintf(w, "complete -c %s -f -n %s -a %s -d %s\n", prog, fishQuote(fn+"_free "+fishQuote(s.path)), fishQuote(sub.name), fishQuote(sub.synopsis))
+		}
+		if len(s.args) > 0 {
+			fmt.Fprintf(w, "complete -c %s -f -n %s -a %s\n", prog, fishQuote(fn+"_free "+fishQuote(s.path)), fishQuote(strings.Join(s.args, " ")))
+		}
+		for _, f := range s.flags {
+			line := fmt.Sprintf("complete -c %s -n %s -o %s -d %s", prog, fishQuote(fn+"_at "+fishQuote(s.path)), fishQuote(f.name), fishQuote(f.usage))
+			switch {
+			case len(f.values) > 0:
+				line += " -x -a " + fishQuote(strings.Join(f.values, " "))
+			case !f.isBool:
+				line += " -r"
+			}
+			fmt.Fprintln(w, line)
+		}
+	})
+}
+
+func fishQuoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fishQuote(v)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,118 @@
+package fanyi
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/subcommands"
+	"github.com/monaco-io/cmd/fanyi/src"
+)
+
+type codeCmd struct {
+	target      string
+	stdout      bool
+	allComments bool
+}
+
+func (*codeCmd) Name() string     { return "code" }
+func (*codeCmd) Synopsis() string { return "Translate marked comments and strings of Go source files" }
+func (*codeCmd) Usage() string {
+	return `fanyi code [-t LANG] [-stdout] [-all-comments] FILE.go...
+
+Translates the comments and string literals marked with //fanyi:translate
+and rewrites each file, formatted with gofmt. The directive on a line of its
+own marks the comments it is part of and the string literals on the line
+below; at the end of a line it marks the string literals on that line:
+
+	// Save 保存文件
+	//fanyi:translate
+	func Save() error {
+		return errors.New("保存失败") //fanyi:translate
+	}
+
+With -all-comments every comment is translated, marked or not. Directives
+such as //go:generate, license headers, generated files and text already in
+the target language are left unchanged.
+
+`
+}
+
+func (c *codeCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.target, "t", "", "Target language (default: first priority language that differs from the source)")
+	f.BoolVar(&c.stdout, "stdout", false, "Print the translated source instead of rewriting the files")
+	f.BoolVar(&c.allComments, "all-comments", false, "Translate every comment, not only those marked with //fanyi:translate")
+}
+
+// FlagValues lists the values of flags for shell completion
+func (*codeCmd) FlagValues(name string) []string {
+	if name == "t" {
+		return (&fanyiCmd{}).FlagValues(name)
+	}
+	return nil
+}
+
+func (c *codeCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() == 0 {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
+
+	cfg, err := src.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	trans, err := src.NewTranslator(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize translator: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	defer trans.Close()
+
+	status := subcommands.ExitSuccess
+	for _, path := range f.Args() {
+		if err := c.translateFile(trans, path); err != nil {
+			fmt.Fprintf(os.Stderr, "Translation error: %v\n", err)
+			status = subcommands.ExitFailure
+		}
+	}
+	return status
+}
+
+// translateFile translates one source file, rewriting it unless the output
+// goes to stdout
+func (c *codeCmd) translateFile(trans *src.Translator, path string) error {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	output, result, err := trans.TranslateGoSource(path, input, c.target, c.allComments)
+	if err != nil {
+		return err
+	}
+	if c.stdout {
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+
+	switch {
+	case result.Generated:
+		fmt.Fprintf(os.Stderr, "- %s: generated, skipped\n", path)
+		return nil
+	case bytes.Equal(input, output):
+		fmt.Fprintf(os.Stderr, "- %s: nothing to translate\n", path)
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, output, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ %s: %d comment(s), %d string(s)\n", path, result.Comments, result.Strings)
+	return nil
+}
@@ -0,0 +1,242 @@
+package fanyi
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/google/subcommands"
+	"github.com/monaco-io/cmd/fanyi/src"
+)
+
+// historySubcommands are the subcommands of fanyi history; without one the
+// recent entries are listed
+var historySubcommands = map[string]func() subcommands.Command{
+	"search": func() subcommands.Command { return &historySearchCmd{} },
+	"show":   func() subcommands.Command { return &historyShowCmd{} },
+	"export": func() subcommands.Command { return &historyExportCmd{} },
+}
+
+type historyCmd struct {
+	limit int
+	json  bool
+}
+
+func (*historyCmd) Name() string     { return "history" }
+func (*historyCmd) Synopsis() string { return "List, search and reprint past translations" }
+func (*historyCmd) Usage() string {
+	return `fanyi history [-n 20] [-json]
+fanyi history search [-json] TERM...
+fanyi history show [-json] N
+fanyi history export [-format json|csv] [-o FILE]
+
+Every translation is appended to history.jsonl in the log directory
+(advanced.log_dir). Disable with history.enabled: false or --no-history.
+
+`
+}
+
+func (c *historyCmd) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&c.limit, "n", 20, "Number of recent entries to list (0 for all)")
+	f.BoolVar(&c.json, "json", false, "Print the entries as JSON")
+}
+
+// Subcommands returns the nested subcommands for shell completion
+func (*historyCmd) Subcommands() []subcommands.Command {
+	names := make([]string, 0, len(historySubcommands))
+	for name := range historySubcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmds := make([]subcommands.Command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, historySubcommands[name]())
+	}
+	return cmds
+}
+
+func (c *historyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() > 0 {
+		newCmd, ok := historySubcommands[f.Arg(0)]
+		if !ok {
+			fmt.Fprint(os.Stderr, c.Usage())
+			return subcommands.ExitUsageError
+		}
+		return runSubcommand(ctx, newCmd(), f.Args()[1:])
+	}
+
+	history, status := openHistory()
+	if history == nil {
+		return status
+	}
+	entries, err := history.Recent(c.limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "History error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return printHistory(entries, c.json)
+}
+
+type historySearchCmd struct {
+	json bool
+}
+
+func (*historySearchCmd) Name() string     { return "search" }
+func (*historySearchCmd) Synopsis() string { return "Find past translations containing all terms" }
+func (*historySearchCmd) Usage() string {
+	return `fanyi history search [-json] TERM...
+	Search inputs and translations, ignoring case.
+
+`
+}
+
+func (c *historySearchCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.json, "json", false, "Print the entries as JSON")
+}
+
+func (c *historySearchCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() == 0 {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
+	history, status := openHistory()
+	if history == nil {
+		return status
+	}
+	entries, err := history.Search(f.Args()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "History error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return printHistory(entries, c.json)
+}
+
+type historyShowCmd struct {
+	json bool
+}
+
+func (*historyShowCmd) Name() string     { return "show" }
+func (*historyShowCmd) Synopsis() string { return "Reprint a past translation without calling the API" }
+func (*historyShowCmd) Usage() string {
+	return `fanyi history show [-json] N
+	Reprint entry N as listed by fanyi history.
+
+`
+}
+
+func (c *historyShowCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.json, "json", false, "Print the entry as JSON")
+}
+
+func (c *historyShowCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	id, err := strconv.Atoi(f.Arg(0))
+	if f.NArg() != 1 || err != nil {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
+	history, status := openHistory()
+	if history == nil {
+		return status
+	}
+	entry, err := history.Get(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "History error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	if err := printOutput(entry, c.json); err != nil {
+		fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type historyExportCmd struct {
+	format string
+	output string
+}
+
+func (*historyExportCmd) Name() string     { return "export" }
+func (*historyExportCmd) Synopsis() string { return "Export the history as JSON or CSV" }
+func (*historyExportCmd) Usage() string {
+	return `fanyi history export [-format json|csv] [-o FILE]
+	Write all entries to stdout or FILE. CSV has one row per output language.
+
+`
+}
+
+func (c *historyExportCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.format, "format", "json", "Export format: json or csv")
+	f.StringVar(&c.output, "o", "", "Output file (default: stdout)")
+}
+
+// FlagValues lists the values of flags for shell completion
+func (*historyExportCmd) FlagValues(name string) []string {
+	if name == "format" {
+		return []string{"json", "csv"}
+	}
+	return nil
+}
+
+func (c *historyExportCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if c.format != "json" && c.format != "csv" {
+		fmt.Fprintf(os.Stderr, "Unknown format %q (available: json, csv)\n", c.format)
+		return subcommands.ExitUsageError
+	}
+	history, status := openHistory()
+	if history == nil {
+		return status
+	}
+	entries, err := history.Recent(0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "History error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+
+	var w io.Writer = os.Stdout
+	if c.output != "" {
+		file, err := os.Create(c.output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Export error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		defer file.Close()
+		w = file
+	}
+	write := entries.WriteJSON
+	if c.format == "csv" {
+		write = entries.WriteCSV
+	}
+	if err := write(w); err != nil {
+		fmt.Fprintf(os.Stderr, "Export error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// openHistory opens the history of the configured log directory; on failure
+// it reports the error and returns the exit status
+func openHistory() (*src.History, subcommands.ExitStatus) {
+	cfg, err := src.Read()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		return nil, subcommands.ExitFailure
+	}
+	return src.NewHistory(cfg), subcommands.ExitSuccess
+}
+
+// printHistory lists entries as text or JSON
+func printHistory(entries src.HistoryList, asJSON bool) subcommands.ExitStatus {
+	if asJSON {
+		if err := entries.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		return subcommands.ExitSuccess
+	}
+	fmt.Println(src.Render(entries))
+	return subcommands.ExitSuccess
+}
@@ -1,21 +1,54 @@
 package fanyi
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 
 	"github.com/google/subcommands"
 	"github.com/monaco-io/cmd/fanyi/src"
 )
 
 type fanyiCmd struct {
-	init    bool
-	noCache bool
+	target        string
+	init          bool
+	noCache       bool
+	watch         bool
+	writeBack     bool
+	style         string
+	context       string
+	contextWindow bool
+	verify        bool
+	alternatives  int
+	explain       bool
+	noDict        bool
+	json          bool
+	format        string
+	scrub         bool
+	dryRun        bool
+	update        bool
+	chunked       bool
+	concurrency   int
+	checkpoint    string
+	model         string
+	endpoint      string
+	temperature   float64
+	maxTokens     int
+	topP          float64
+	seed          int
+	image         string
+	romanize      bool
+	noHistory     bool
+	save          bool
+	gitMsg        string
 }
 
 // New returns a new fanyi command.
@@ -23,26 +56,154 @@ func New() subcommands.Command {
 	return &fanyiCmd{}
 }
 
+// subcommands of fanyi, selected by the first argument; any other first
+// argument, or any argument after "--", is treated as text to translate
+var fanyiSubcommands = map[string]func() subcommands.Command{
+	"serve":   func() subcommands.Command { return &serveCmd{} },
+	"code":    func() subcommands.Command { return &codeCmd{} },
+	"history": func() subcommands.Command { return &historyCmd{} },
+	"vocab":   func() subcommands.Command { return &vocabCmd{} },
+}
+
+// Subcommands returns the nested subcommands for shell completion
+func (*fanyiCmd) Subcommands() []subcommands.Command {
+	names := make([]string, 0, len(fanyiSubcommands))
+	for name := range fanyiSubcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmds := make([]subcommands.Command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, fanyiSubcommands[name]())
+	}
+	return cmds
+}
+
+// FlagValues lists the values of flags for shell completion
+func (*fanyiCmd) FlagValues(name string) []string {
+	switch name {
+	case "t", "target-lang":
+		cfg, err := src.Read()
+		if err != nil {
+			cfg = src.DefaultConfig()
+		}
+		return cfg.Languages.Common
+	case "style":
+		cfg, err := src.Read()
+		if err != nil {
+			cfg = src.DefaultConfig()
+		}
+		return cfg.StyleNames()
+	case "format":
+		return []string{"text", "html", "xml"}
+	}
+	return nil
+}
+
 func (*fanyiCmd) Name() string     { return "fanyi" }
 func (*fanyiCmd) Synopsis() string { return "AI-Powered CLI Translation Tool" }
 func (*fanyiCmd) Usage() string {
 	return `fanyi [OPTIONS] [TEXT]
+fanyi [OPTIONS] -- TEXT
+fanyi serve [--addr :8080]
+fanyi history [search TERM... | show N | export]
+fanyi vocab [delete ID... | export | quiz]
+fanyi code [-t LANG] FILE.go...
+fanyi --git-msg FILE [-t LANG]
+fanyi --update OLD NEW OLD_TRANSLATION > NEW_TRANSLATION
 
 OPTIONS:
+	-t, --target-lang <LANG>    Target language (zh, en, ja, ko, es, etc.)
 	--init                      Initialize config at ~/.config/fanyi/config.yaml
+	--model <NAME>              Model to use (overrides api.model)
+	--endpoint <URL>            Chat completions endpoint (overrides api.endpoint)
+	--temperature <T>           Sampling temperature, 0 to 2
+	--max-tokens <N>            Maximum tokens of a response
+	--top-p <P>                 Nucleus sampling probability, 0 to 1
+	--seed <N>                  Sampling seed, for reproducible output where supported
+	--style <NAME>              Translation style (formal, casual, technical, marketing)
+	--context <TEXT>            Background information about the text
+	--context-window            Translate paragraph by paragraph with document context
+	--verify                    Back-translate and score the translation
+	--romanize                  Add pinyin, romaji or Revised Romanization to zh, ja and ko output
+	--alternatives <N>          Show N ranked alternative translations
+	--explain                   Show a word-by-word gloss and grammar notes
+	--no-dict                   Translate single words instead of looking them up
+	--json                      Print the result as JSON
+	--format <FORMAT>           Input format: text, html or xml (markup is preserved)
+	--no-cache                  Skip the translation cache
+	--no-history                Do not record this translation in the history
+	--save                      Save the translation to the vocabulary notebook (see fanyi vocab)
+	--scrub                     Replace personal data and secrets with placeholders before sending
+	--dry-run                   Show the requests that would be sent without sending them
+	--update                    Update a translation after its source changed (see above)
+	--chunked                   Split long documents into chunks translated concurrently
+	--concurrency <N>           Number of chunks translated at once (with --chunked)
+	--checkpoint <FILE>         Resume an interrupted chunked translation (implies --chunked)
+	--image <FILE>              Translate the text in a screenshot or photo (vision models)
+	--git-msg <FILE>            Translate a commit message file in place (commit-msg hook)
+	--watch-clipboard           Translate new clipboard content as it appears
+	--write-back                With --watch-clipboard, copy the translation to the clipboard
 
 EXAMPLES:
   fanyi hello world
+  fanyi -t ja hello world
 	echo "hello" | fanyi
+  cat article.md | fanyi -t zh > article.zh.md
+  fanyi --format html -t ja < help.html > help.ja.html
+  fanyi --checkpoint book.ckpt -t zh < book.txt > book.zh.txt
+  fanyi --image screenshot.png --model gpt-4o -t en
 
 `
 }
 
 func (c *fanyiCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.target, "t", "", "Target language (default: priority languages)")
+	f.StringVar(&c.target, "target-lang", "", "Target language (default: priority languages)")
 	f.BoolVar(&c.init, "init", false, "Initialize config file (~/.config/fanyi/config.yaml)")
+	f.StringVar(&c.model, "model", "", "Model to use (overrides api.model)")
+	f.StringVar(&c.endpoint, "endpoint", "", "Chat completions endpoint (overrides api.endpoint)")
+	f.Float64Var(&c.temperature, "temperature", 0, "Sampling temperature between 0 and 2 (overrides api.temperature)")
+	f.IntVar(&c.maxTokens, "max-tokens", 0, "Maximum tokens of a response (overrides api.max_tokens)")
+	f.Float64Var(&c.topP, "top-p", 0, "Nucleus sampling probability between 0 and 1 (overrides api.top_p)")
+	f.IntVar(&c.seed, "seed", 0, "Sampling seed for reproducible output (overrides api.seed)")
+	f.StringVar(&c.style, "style", "", "Translation style preset (formal, casual, technical, marketing)")
+	f.StringVar(&c.context, "context", "", "Background information about the text")
+	f.BoolVar(&c.contextWindow, "context-window", false, "Translate paragraph by paragraph, carrying document context between requests")
+	f.BoolVar(&c.verify, "verify", false, "Back-translate the result and flag low-confidence segments")
+	f.BoolVar(&c.romanize, "romanize", false, "Show pinyin, Hepburn romaji or Revised Romanization for Chinese, Japanese and Korean translations")
+	f.IntVar(&c.alternatives, "alternatives", 0, "Number of ranked alternative translations to show")
+	f.BoolVar(&c.explain, "explain", false, "Show a word-by-word gloss and notes on grammar and idioms")
+	f.BoolVar(&c.noDict, "no-dict", false, "Translate single words instead of showing a dictionary entry")
+	f.BoolVar(&c.json, "json", false, "Print the result as JSON")
+	f.StringVar(&c.format, "format", "text", "Input format: text, html or xml")
+	f.BoolVar(&c.noCache, "no-cache", false, "Skip the translation cache")
+	f.BoolVar(&c.noHistory, "no-history", false, "Do not record this translation in the history")
+	f.BoolVar(&c.save, "save", false, "Save the translation to the vocabulary notebook for review with fanyi vocab")
+	f.BoolVar(&c.scrub, "scrub", false, "Replace emails, phone numbers, IPs, keys and card numbers with placeholders before sending")
+	f.BoolVar(&c.dryRun, "dry-run", false, "Print the requests that would be sent to the API and exit")
+	f.BoolVar(&c.update, "update", false, "Re-translate only the changed paragraphs: --update old.md new.md old.zh.md")
+	f.BoolVar(&c.chunked, "chunked", false, "Split long documents at sentence boundaries and translate the chunks concurrently")
+	f.IntVar(&c.concurrency, "concurrency", 0, "Number of chunks translated at once (default: chunking.concurrency)")
+	f.StringVar(&c.checkpoint, "checkpoint", "", "Checkpoint file to resume an interrupted chunked translation (implies --chunked)")
+	f.StringVar(&c.image, "image", "", "Image file whose visible text is extracted and translated by a vision model")
+	f.StringVar(&c.gitMsg, "git-msg", "", "Commit message file to translate in place, for use in a commit-msg hook")
+	f.BoolVar(&c.watch, "watch-clipboard", false, "Watch the clipboard and translate new content")
+	f.BoolVar(&c.writeBack, "write-back", false, "Write the translation back to the clipboard (with --watch-clipboard)")
 }
 
-func (c *fanyiCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+func (c *fanyiCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	// Dispatch to a subcommand
+	if f.NArg() > 0 && !afterTerminator(f) {
+		if newCmd, ok := fanyiSubcommands[f.Arg(0)]; ok {
+			if f.NFlag() > 0 {
+				fmt.Fprintf(os.Stderr, "Options must follow the subcommand: fanyi %s [OPTIONS]\n", f.Arg(0))
+				return subcommands.ExitUsageError
+			}
+			return runSubcommand(ctx, newCmd(), f.Args()[1:])
+		}
+	}
+
 	// Init config and exit
 	if c.init {
 		if err := initConfig(); err != nil {
@@ -53,15 +214,76 @@ func (c *fanyiCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 		return subcommands.ExitSuccess
 	}
 
-	// Load configuration
-	cfg, err := src.Load()
+	// Load configuration; it is validated once the flags are applied
+	cfg, err := src.Read()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
-		fmt.Fprintf(os.Stderr, "\nQuick fix: Set your API key with:\n")
-		fmt.Fprintf(os.Stderr, "  export FANYI_API_KEY=\"sk-your-api-key-here\"\n\n")
 		return subcommands.ExitFailure
 	}
 
+	// Apply command line overrides
+	c.applyModelFlags(f, cfg)
+	if c.style != "" {
+		cfg.Advanced.Style = c.style
+	}
+	if c.context != "" {
+		cfg.Advanced.Context = c.context
+	}
+	if c.noCache {
+		cfg.Cache.Enabled = false
+	}
+	if c.noHistory || c.dryRun {
+		cfg.History.Enabled = false
+	}
+	if c.contextWindow {
+		cfg.ContextWindow.Enabled = true
+	}
+	if c.verify {
+		cfg.Verify.Enabled = true
+	}
+	if c.romanize {
+		cfg.Advanced.Romanize = true
+	}
+	if c.writeBack {
+		cfg.Clipboard.WriteBack = true
+	}
+	if c.scrub {
+		cfg.Scrub.Enabled = true
+	}
+	if c.concurrency != 0 {
+		cfg.Chunking.Concurrency = c.concurrency
+	}
+	if c.checkpoint != "" {
+		c.chunked = true
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		if cfg.API.Key == "" {
+			fmt.Fprintf(os.Stderr, "\nQuick fix: Set your API key with:\n")
+			fmt.Fprintf(os.Stderr, "  export FANYI_API_KEY=\"sk-your-api-key-here\"\n\n")
+			return subcommands.ExitFailure
+		}
+		return subcommands.ExitUsageError
+	}
+	switch c.format {
+	case "text", "html", "xml":
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %q (available: text, html, xml)\n", c.format)
+		return subcommands.ExitUsageError
+	}
+	if c.dryRun && (c.format != "text" || c.watch || c.update || c.alternatives > 0 || c.explain) {
+		fmt.Fprintln(os.Stderr, "--dry-run only supports plain text translation")
+		return subcommands.ExitUsageError
+	}
+	if c.chunked && (c.format != "text" || c.watch || c.update || c.dryRun || c.alternatives > 0 || c.explain) {
+		fmt.Fprintln(os.Stderr, "--chunked only supports plain text translation")
+		return subcommands.ExitUsageError
+	}
+	if c.save && (c.format != "text" || c.watch || c.update || c.chunked || c.dryRun || c.image != "" || c.gitMsg != "") {
+		fmt.Fprintln(os.Stderr, "--save only supports plain text translation")
+		return subcommands.ExitUsageError
+	}
+
 	// Create translator
 	trans, err := src.NewTranslator(cfg)
 	if err != nil {
@@ -70,6 +292,63 @@ func (c *fanyiCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 	}
 	defer trans.Close()
 
+	// Translate a commit message in place
+	if c.gitMsg != "" {
+		if f.NArg() > 0 || c.format != "text" || c.watch || c.update || c.chunked || c.dryRun || c.image != "" || c.alternatives > 0 || c.explain {
+			fmt.Fprintln(os.Stderr, "--git-msg cannot be combined with text input or other modes")
+			return subcommands.ExitUsageError
+		}
+		return c.translateCommitMessage(trans)
+	}
+
+	// Translate the text in an image
+	if c.image != "" {
+		if f.NArg() > 0 || c.format != "text" || c.watch || c.update || c.chunked || c.dryRun || c.alternatives > 0 || c.explain {
+			fmt.Fprintln(os.Stderr, "--image cannot be combined with text input or other modes")
+			return subcommands.ExitUsageError
+		}
+		// The text in the image reaches the model as pixels, which neither
+		// the scrubber nor the hooks can rewrite
+		if c.scrub {
+			fmt.Fprintln(os.Stderr, "--scrub cannot be combined with --image")
+			return subcommands.ExitUsageError
+		}
+		if len(cfg.Hooks) > 0 || cfg.Scrub.Enabled {
+			fmt.Fprintln(os.Stderr, "Warning: hooks and scrubbing do not apply to the text in images")
+		}
+		result, err := trans.TranslateImage(c.image, c.target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Translation error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		if err := c.print(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		c.record(trans, trans.NewHistoryEntry(c.image, result))
+		return subcommands.ExitSuccess
+	}
+
+	// Watch the clipboard until interrupted
+	if c.watch {
+		return c.watchClipboard(ctx, trans)
+	}
+
+	// Update an existing translation
+	if c.update {
+		return c.updateTranslation(trans, f.Args(), cfg.History.Enabled)
+	}
+
+	// Translate long documents chunk by chunk
+	if c.chunked {
+		return c.translateChunked(trans, f.Args())
+	}
+
+	// Translate markup documents as a whole
+	if c.format != "text" {
+		return c.translateMarkup(trans, f.Args())
+	}
+
 	// Get text from arguments or stdin
 	var text string
 
@@ -81,36 +360,332 @@ func (c *fanyiCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 		stat, _ := os.Stdin.Stat()
 		if (stat.Mode() & os.ModeCharDevice) == 0 {
 			// Reading from pipe
-			scanner := bufio.NewScanner(os.Stdin)
-			var lines []string
-			for scanner.Scan() {
-				lines = append(lines, scanner.Text())
+			// Keep a copy of the input only for the history entry
+			var input strings.Builder
+			var in io.Reader = os.Stdin
+			if cfg.History.Enabled {
+				in = io.TeeReader(os.Stdin, &input)
+			}
+			sc := src.NewParagraphScanner(in)
+			multi, err := sc.MultiParagraph()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Input error: %v\n", err)
+				return subcommands.ExitFailure
+			}
+
+			// Stream documents paragraph by paragraph
+			if multi && c.alternatives == 0 && !c.explain && !c.verify && !cfg.Advanced.Romanize && !c.json && !c.dryRun && !c.save {
+				var output strings.Builder
+				var out io.Writer = os.Stdout
+				if cfg.History.Enabled {
+					out = io.MultiWriter(os.Stdout, &output)
+				}
+				lang, err := trans.TranslateStream(sc, out, c.target)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "\nTranslation error: %v\n", err)
+					return subcommands.ExitFailure
+				}
+				if cfg.History.Enabled {
+					c.record(trans, trans.NewDocumentHistoryEntry(input.String(), lang, output.String()))
+				}
+				return subcommands.ExitSuccess
+			}
+
+			if text, err = sc.ReadAll(); err != nil {
+				fmt.Fprintf(os.Stderr, "Input error: %v\n", err)
+				return subcommands.ExitFailure
 			}
-			text = strings.Join(lines, "\n")
 		}
 	}
 
+	// Trim whitespace
+	text = strings.TrimSpace(text)
+
 	// Validate input
 	if text == "" {
 		fmt.Fprint(os.Stderr, c.Usage())
 		return subcommands.ExitUsageError
 	}
 
-	// Trim whitespace
-	text = strings.TrimSpace(text)
-
 	// Translate
-	result, err := trans.Translate(text, "")
+	var result src.Output
+	switch {
+	case c.dryRun:
+		result, err = trans.DryRun(text, c.target)
+	case c.alternatives > 0:
+		result, err = trans.Alternatives(text, c.target, c.alternatives)
+	case c.explain:
+		result, err = trans.Explain(text, c.target)
+	case !c.noDict && !c.verify && !cfg.Advanced.Romanize && len(cfg.Hooks) == 0 && src.IsSingleWord(text):
+		result, err = trans.Lookup(text, c.target)
+	default:
+		result, err = trans.Translate(text, c.target)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Translation error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+
+	if err := c.print(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	entry := trans.NewHistoryEntry(text, result)
+	c.record(trans, entry)
+	if c.save {
+		return saveVocab(entry)
+	}
+
+	return subcommands.ExitSuccess
+}
+
+// record appends a translation to the history, warning on failure
+func (c *fanyiCmd) record(trans *src.Translator, entry *src.HistoryEntry) {
+	if err := trans.Record(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "History error: %v\n", err)
+	}
+}
+
+// applyModelFlags overrides the model settings given on the command line;
+// flags left at their defaults keep the values from env, file or defaults
+func (c *fanyiCmd) applyModelFlags(f *flag.FlagSet, cfg *src.Config) {
+	f.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "model":
+			cfg.API.Model = c.model
+			cfg.SetSource("api.model", "flag --model")
+		case "endpoint":
+			cfg.API.Endpoint = c.endpoint
+			cfg.SetSource("api.endpoint", "flag --endpoint")
+		case "temperature":
+			cfg.API.Temperature = c.temperature
+			cfg.SetSource("api.temperature", "flag --temperature")
+		case "max-tokens":
+			cfg.API.MaxTokens = c.maxTokens
+			cfg.SetSource("api.max_tokens", "flag --max-tokens")
+		case "top-p":
+			cfg.API.TopP = c.topP
+			cfg.SetSource("api.top_p", "flag --top-p")
+		case "seed":
+			seed := c.seed
+			cfg.API.Seed = &seed
+			cfg.SetSource("api.seed", "flag --seed")
+		}
+	})
+}
+
+// print writes a result to stdout as text or JSON
+func (c *fanyiCmd) print(result src.Output) error {
+	return printOutput(result, c.json)
+}
+
+// printOutput writes a result to stdout as text or JSON
+func printOutput(result src.Output, asJSON bool) error {
+	if asJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Println(src.Render(result))
+	return nil
+}
+
+// translateCommitMessage translates the commit message file given with
+// --git-msg and writes it back
+func (c *fanyiCmd) translateCommitMessage(trans *src.Translator) subcommands.ExitStatus {
+	data, err := os.ReadFile(c.gitMsg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Input error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	output, lang, err := trans.TranslateCommitMessage(string(data), c.target)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Translation error: %v\n", err)
 		return subcommands.ExitFailure
 	}
+	if output == string(data) {
+		return subcommands.ExitSuccess
+	}
+	if err := os.WriteFile(c.gitMsg, []byte(output), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Output error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	c.record(trans, trans.NewDocumentHistoryEntry(string(data), lang, output))
+	return subcommands.ExitSuccess
+}
 
-	fmt.Println(result)
+// translateMarkup translates an HTML or XML document read from the
+// arguments or stdin and writes the document to stdout
+func (c *fanyiCmd) translateMarkup(trans *src.Translator, args []string) subcommands.ExitStatus {
+	input := strings.Join(args, " ")
+	if len(args) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Input error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		input = string(data)
+	}
+	if strings.TrimSpace(input) == "" {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
 
+	translate := trans.TranslateHTML
+	if c.format == "xml" {
+		translate = trans.TranslateXML
+	}
+	output, lang, err := translate(input, c.target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Translation error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Print(output)
+	if !strings.HasSuffix(output, "\n") {
+		fmt.Println()
+	}
+	c.record(trans, trans.NewDocumentHistoryEntry(input, lang, output))
 	return subcommands.ExitSuccess
 }
 
+// translateChunked translates a document read from the arguments or stdin in
+// concurrent chunks, reporting progress on stderr
+func (c *fanyiCmd) translateChunked(trans *src.Translator, args []string) subcommands.ExitStatus {
+	input := strings.Join(args, " ")
+	if len(args) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Input error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		input = string(data)
+	}
+	if strings.TrimSpace(input) == "" {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
+
+	output, lang, err := trans.TranslateChunked(input, c.target, src.ChunkOptions{
+		Checkpoint: c.checkpoint,
+		Progress: func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rTranslated %d/%d chunks", done, total)
+		},
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Translation error: %v\n", err)
+		if c.checkpoint != "" {
+			fmt.Fprintf(os.Stderr, "Run the same command again to resume from %s\n", c.checkpoint)
+		}
+		return subcommands.ExitFailure
+	}
+	fmt.Print(output)
+	if !strings.HasSuffix(output, "\n") {
+		fmt.Println()
+	}
+	c.record(trans, trans.NewDocumentHistoryEntry(input, lang, output))
+	return subcommands.ExitSuccess
+}
+
+// updateTranslation writes the translation of a changed source document to
+// stdout, reusing the unchanged paragraphs of the previous translation, and a
+// summary of the changes to stderr. The documents are only kept in memory
+// when history is enabled.
+func (c *fanyiCmd) updateTranslation(trans *src.Translator, args []string, history bool) subcommands.ExitStatus {
+	if len(args) != 3 {
+		fmt.Fprintln(os.Stderr, "Usage: fanyi --update OLD_SOURCE NEW_SOURCE OLD_TRANSLATION")
+		return subcommands.ExitUsageError
+	}
+	files := make([]*os.File, len(args))
+	for i, name := range args {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Input error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		defer file.Close()
+		files[i] = file
+	}
+
+	var source, output strings.Builder
+	var in io.Reader = files[1]
+	var out io.Writer = os.Stdout
+	if history {
+		in, out = io.TeeReader(files[1], &source), io.MultiWriter(os.Stdout, &output)
+	}
+	summary, err := trans.Update(files[0], in, files[2], out, c.target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Update error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	if history {
+		c.record(trans, trans.NewDocumentHistoryEntry(source.String(), summary.Language, output.String()))
+	}
+	if c.json {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		fmt.Fprintln(os.Stderr, string(data))
+		return subcommands.ExitSuccess
+	}
+	fmt.Fprintln(os.Stderr, summary.Format(false))
+	return subcommands.ExitSuccess
+}
+
+// watchClipboard translates clipboard content until interrupted
+func (c *fanyiCmd) watchClipboard(ctx context.Context, trans *src.Translator) subcommands.ExitStatus {
+	cb, err := src.DetectClipboard()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Clipboard error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintln(os.Stderr, "Watching clipboard, press Ctrl+C to stop...")
+	err = trans.WatchClipboard(ctx, cb, c.target, func(result src.Output) {
+		if err := c.print(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+		}
+		fmt.Println()
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Clipboard error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// afterTerminator reports whether the arguments left in f follow a "--". The
+// flag package drops the "--", so it is looked up on the command line.
+func afterTerminator(f *flag.FlagSet) bool {
+	i := len(os.Args) - f.NArg() - 1
+	return i > 0 && os.Args[i] == "--"
+}
+
+// runSubcommand parses args with the subcommand's flags and executes it
+func runSubcommand(ctx context.Context, cmd subcommands.Command, args []string) subcommands.ExitStatus {
+	fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, cmd.Usage())
+		fs.PrintDefaults()
+	}
+	cmd.SetFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return subcommands.ExitSuccess
+		}
+		return subcommands.ExitUsageError
+	}
+	return cmd.Execute(ctx, fs)
+}
+
 // initConfig creates ~/.config/fanyi/config.yaml from the bundled example if it does not exist.
 func initConfig() error {
 	home, err := os.UserHomeDir()
@@ -0,0 +1,61 @@
+package fanyi
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/google/subcommands"
+	"github.com/monaco-io/cmd/fanyi/src"
+)
+
+type serveCmd struct {
+	addr string
+}
+
+func (*serveCmd) Name() string     { return "serve" }
+func (*serveCmd) Synopsis() string { return "Serve the translator as a local HTTP API" }
+func (*serveCmd) Usage() string {
+	return `fanyi serve [--addr :8080]
+
+ENDPOINTS:
+	POST /translate    {"text": "...", "targets": ["zh", "en"]}
+	                   or LibreTranslate {"q": "...", "source": "auto", "target": "zh"}
+	GET  /languages    LibreTranslate language list
+
+`
+}
+
+func (c *serveCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.addr, "addr", "", "Listen address (default from server.addr, :8080)")
+}
+
+func (c *serveCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	cfg, err := src.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	if c.addr != "" {
+		cfg.Server.Addr = c.addr
+	}
+
+	trans, err := src.NewTranslator(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize translator: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	defer trans.Close()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := src.NewServer(trans).ListenAndServe(ctx, cfg.Server.Addr); err != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
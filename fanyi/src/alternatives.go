@@ -0,0 +1,157 @@
+package src
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSamplingRounds limits repeated requests when the provider ignores n
+const maxSamplingRounds = 3
+
+// Alternative is one ranked translation variant
+type Alternative struct {
+	Text  string `json:"text"`
+	Note  string `json:"note"`
+	Votes int    `json:"votes"`
+}
+
+// Alternatives holds the translation variants into one language
+type Alternatives struct {
+	Language string        `json:"language"`
+	Variants []Alternative `json:"variants"`
+	Backend  string        `json:"backend,omitempty"`
+}
+
+// Alternatives samples up to n distinct translations and ranks them by how
+// often the model produced them
+func (c *Client) Alternatives(text, targetLanguage string, n int) ([]Alternative, error) {
+	messages := c.buildTemplateMessages(c.config.Advanced.AlternativesTemplate, defaultAlternativesTemplate, text, targetLanguage)
+
+	request := c.newRequest(messages)
+	request.Temperature = max(request.Temperature, 0.9)
+
+	var (
+		variants []Alternative
+		index    = map[string]int{}
+	)
+	for round := 0; round < maxSamplingRounds && len(variants) < n; round++ {
+		request.N = n - len(variants)
+		resp, err := c.Complete(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, choice := range resp.Choices {
+			var answer struct {
+				Translation string `json:"translation"`
+				Note        string `json:"note"`
+			}
+			if err := parseJSONAnswer(choice.Message.Content.Text, &answer); err != nil {
+				// Treat a plain answer as a variant without a note
+				answer.Translation = choice.Message.Content.Text
+			}
+			key := strings.TrimSpace(answer.Translation)
+			if key == "" {
+				continue
+			}
+			if i, ok := index[key]; ok {
+				variants[i].Votes++
+				continue
+			}
+			index[key] = len(variants)
+			variants = append(variants, Alternative{Text: key, Note: strings.TrimSpace(answer.Note), Votes: 1})
+		}
+	}
+
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("no alternatives returned")
+	}
+
+	// A stable sort keeps the sampling order for ties
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Votes > variants[j].Votes })
+	if len(variants) > n {
+		variants = variants[:n]
+	}
+	return variants, nil
+}
+
+// AlternativesResult holds the alternative translations of one input text
+type AlternativesResult struct {
+	Original  string          `json:"original"`
+	Languages []*Alternatives `json:"languages"`
+}
+
+// Alternatives translates text to the target language(s) with n ranked variants each
+func (t *Translator) Alternatives(text, targetLang string, n int) (*AlternativesResult, error) {
+	result := &AlternativesResult{Original: text}
+	for _, lang := range t.targetLanguages(targetLang) {
+		t.logger.Debug("calling API", "lang", lang, "alternatives", n)
+		variants, client, err := t.alternatives(text, lang, n)
+		if err != nil {
+			if targetLang != "" {
+				return nil, fmt.Errorf("translation failed: %w", err)
+			}
+			t.logger.Error("failed to translate", "lang", lang, "err", err)
+			continue
+		}
+		result.Languages = append(result.Languages, &Alternatives{Language: lang, Variants: variants, Backend: client.Name()})
+	}
+
+	if len(result.Languages) == 0 {
+		return nil, fmt.Errorf("failed to translate to any language")
+	}
+	return result, nil
+}
+
+// alternatives asks the backends for n variants of text in lang, passing the
+// text through the pre hooks and each variant through the post hooks
+func (t *Translator) alternatives(text, lang string, n int) ([]Alternative, *Client, error) {
+	var variants []Alternative
+	client, err := t.hooked([]string{text}, lang, func(c *Client, texts []string) ([][]*string, error) {
+		var err error
+		if variants, err = c.Alternatives(texts[0], lang, n); err != nil {
+			return nil, err
+		}
+		outs := make([]*string, len(variants))
+		for i := range variants {
+			outs[i] = &variants[i].Text
+		}
+		return [][]*string{outs}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return variants, client, nil
+}
+
+// Format renders the alternatives for the terminal
+func (r *AlternativesResult) Format(color bool) string {
+	blocks := make([]string, 0, len(r.Languages))
+	for _, a := range r.Languages {
+		blocks = append(blocks, formatAlternatives(a, color))
+	}
+	return formatMultiOutput(r.Original, blocks, color)
+}
+
+// targetLanguages returns targetLang or the priority languages if it is empty
+func (t *Translator) targetLanguages(targetLang string) []string {
+	if targetLang != "" {
+		return []string{targetLang}
+	}
+	return t.config.Languages.Priority
+}
+
+func formatAlternatives(a *Alternatives, color bool) string {
+	var b strings.Builder
+	b.WriteString(c(getLanguageName(a.Language)+":", colorGreen+colorBold, color))
+	for i, v := range a.Variants {
+		fmt.Fprintf(&b, "\n  %s %s", c(fmt.Sprintf("%d.", i+1), colorMagenta, color), v.Text)
+		if v.Votes > 1 {
+			b.WriteString(" " + c(fmt.Sprintf("×%d", v.Votes), colorGray, color))
+		}
+		if v.Note != "" {
+			b.WriteString("\n     " + c(v.Note, colorDim, color))
+		}
+	}
+	return b.String()
+}
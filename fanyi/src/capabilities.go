@@ -0,0 +1,115 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Model profiles selected with api.profile; empty selects by model name
+const (
+	ProfileChat      = "chat"
+	ProfileReasoning = "reasoning"
+)
+
+// modelProfile describes how the requests of a model family differ from the
+// OpenAI chat completion defaults
+type modelProfile struct {
+	// maxCompletionTokens sends the response limit as max_completion_tokens,
+	// which also covers the hidden reasoning tokens
+	maxCompletionTokens bool
+	// temperature, when set, replaces the configured temperature, for
+	// models that reject any other value
+	temperature *float64
+	// noTopP drops top_p, which the model rejects
+	noTopP bool
+}
+
+var one = 1.0
+
+// profiles are the known model profiles by name
+var profiles = map[string]modelProfile{
+	ProfileChat:      {},
+	ProfileReasoning: {maxCompletionTokens: true, temperature: &one, noTopP: true},
+}
+
+// reasoningModelRe matches model names of OpenAI reasoning models, with an
+// optional gateway prefix such as "openai/"
+var reasoningModelRe = regexp.MustCompile(`^(?:.*/)?(?:o[1-9](?:-|$)|gpt-5)`)
+
+// profile returns the profile of the client's model: the configured one, or
+// one chosen by model name
+func (c *Client) profile() modelProfile {
+	if p, ok := profiles[c.config.API.Profile]; ok {
+		return p
+	}
+	model := c.config.API.Model
+	if model == "" {
+		model = c.config.API.Deployment
+	}
+	if reasoningModelRe.MatchString(strings.ToLower(model)) {
+		return profiles[ProfileReasoning]
+	}
+	return profiles[ProfileChat]
+}
+
+// adapt rewrites request for the model profile
+func (p modelProfile) adapt(request ChatRequest) ChatRequest {
+	if p.maxCompletionTokens && request.MaxTokens > 0 {
+		request.MaxCompletionTokens, request.MaxTokens = request.MaxTokens, 0
+	}
+	if p.temperature != nil {
+		request.Temperature = *p.temperature
+	}
+	if p.noTopP {
+		request.TopP = 0
+	}
+	return request
+}
+
+// validateProfile checks the profile name
+func (api *APIConfig) validateProfile() error {
+	if _, ok := profiles[api.Profile]; api.Profile != "" && !ok {
+		return fmt.Errorf("unknown profile %q (available: %s, %s)", api.Profile, ProfileChat, ProfileReasoning)
+	}
+	return nil
+}
+
+// reasoningTags are the tags local reasoning models wrap the reasoning they
+// put before the answer in
+var reasoningTags = []string{"think", "thinking", "reasoning"}
+
+// stripReasoning removes reasoning from a response message: the separate
+// reasoning_content field and the <think> blocks leading the content. Tags
+// further on belong to the answer, such as a translated document, and are
+// kept. A leading block that was never closed, because the response was cut
+// off, leaves no answer.
+func stripReasoning(m *Message) {
+	m.ReasoningContent = ""
+	content := strings.TrimSpace(m.Content.Text)
+	for tag := leadingReasoningTag(content); tag != ""; tag = leadingReasoningTag(content) {
+		closing := "</" + tag + ">"
+		i := strings.Index(content, closing)
+		if i < 0 {
+			content = ""
+			break
+		}
+		content = strings.TrimSpace(content[i+len(closing):])
+	}
+	// Some servers strip the opening tag but keep the closing one, which
+	// then comes before any tag of the answer
+	if i := strings.Index(content, "</think>"); i >= 0 && !strings.Contains(content[:i], "<") {
+		content = content[i+len("</think>"):]
+	}
+	m.Content = TextContent(strings.TrimSpace(content))
+}
+
+// leadingReasoningTag returns the reasoning tag content starts with, if any
+func leadingReasoningTag(content string) string {
+	for _, tag := range reasoningTags {
+		if strings.HasPrefix(content, "<"+tag+">") {
+			return tag
+		}
+	}
+	return ""
+}
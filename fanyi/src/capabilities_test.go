@@ -0,0 +1,91 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientAdaptsRequestToModel(t *testing.T) {
+	tests := []struct {
+		model, profile string
+		want           map[string]interface{}
+	}{
+		{"gpt-4", "", map[string]interface{}{"max_tokens": 1000.0, "temperature": 0.7, "top_p": 0.9}},
+		{"o3-mini", "", map[string]interface{}{"max_completion_tokens": 1000.0, "temperature": 1.0}},
+		{"openai/gpt-5", "", map[string]interface{}{"max_completion_tokens": 1000.0, "temperature": 1.0}},
+		{"my-deployment", ProfileReasoning, map[string]interface{}{"max_completion_tokens": 1000.0, "temperature": 1.0}},
+		{"o1", ProfileChat, map[string]interface{}{"max_tokens": 1000.0, "temperature": 0.7, "top_p": 0.9}},
+	}
+	for _, tt := range tests {
+		var body map[string]interface{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body = nil
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(ChatResponse{Choices: []Choice{{Message: Message{Content: TextContent("ok")}}}})
+		}))
+		cfg := testConfig(srv.URL)
+		cfg.API.Model, cfg.API.Profile, cfg.API.TopP = tt.model, tt.profile, 0.9
+		if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+			t.Fatal(err)
+		}
+		srv.Close()
+
+		for _, key := range []string{"max_tokens", "max_completion_tokens", "temperature", "top_p"} {
+			if body[key] != tt.want[key] {
+				t.Errorf("%s/%q: %s = %v, want %v", tt.model, tt.profile, key, body[key], tt.want[key])
+			}
+		}
+	}
+}
+
+func TestClientStripsReasoning(t *testing.T) {
+	tests := []struct {
+		message Message
+		want    string
+	}{
+		{Message{Content: TextContent("你好"), ReasoningContent: "The user wants Chinese."}, "你好"},
+		{Message{Content: TextContent("<think>\nhello means 你好\n</think>\n\n你好")}, "你好"},
+		{Message{Content: TextContent("hello means 你好\n</think>\n你好")}, "你好"},
+		{Message{Content: TextContent("<thinking>a</thinking>\n<think>b</think>你好")}, "你好"},
+		{Message{Content: TextContent("<p>我<think>认为</think>是</p>")}, "<p>我<think>认为</think>是</p>"},
+		{Message{Content: TextContent("原文写着 <think>")}, "原文写着 <think>"},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(ChatResponse{Choices: []Choice{{Message: tt.message}}})
+		}))
+		got, err := NewClient(testConfig(srv.URL)).Translate("hello", "zh")
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("got %q from %+v, want %q", got, tt.message, tt.want)
+		}
+	}
+}
+
+func TestClientReasoningCutOff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []Choice{{
+			Message:      Message{Content: TextContent("<think>hello is a greeting, so")},
+			FinishReason: "length",
+		}}})
+	}))
+	defer srv.Close()
+	_, err := NewClient(testConfig(srv.URL)).Translate("hello", "zh")
+	if err == nil || !strings.Contains(err.Error(), "max_tokens") {
+		t.Errorf("got %v, want an error suggesting a larger max_tokens", err)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	cfg := testConfig("https://example.com")
+	cfg.API.Profile = "thinking"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
@@ -0,0 +1,223 @@
+package src
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ChunkOptions controls TranslateChunked
+type ChunkOptions struct {
+	// Checkpoint is a file recording finished chunks, so that an interrupted
+	// translation of the same input resumes where it stopped; it is removed
+	// once the translation is complete
+	Checkpoint string
+	// Progress is called after each chunk with the number of finished chunks
+	Progress func(done, total int)
+}
+
+// chunkCheckpoint is the content of a checkpoint file
+type chunkCheckpoint struct {
+	Input        string         `json:"input"`
+	Language     string         `json:"language"`
+	Budget       int            `json:"budget"`
+	Translations map[int]string `json:"translations"`
+}
+
+// TranslateChunked translates a document of any length into targetLang. The
+// text is split into sentences, packed into chunks of at most the configured
+// token budget, translated concurrently and reassembled in order with the
+// original whitespace. If targetLang is empty it is chosen from the priority
+// languages; the language used is returned.
+func (t *Translator) TranslateChunked(text, targetLang string, opts ChunkOptions) (string, string, error) {
+	if targetLang == "" {
+		targetLang = t.defaultTarget(text)
+	}
+	budget := t.chunkBudget()
+	chunks := packSpans(segmentDocument(text), budget)
+
+	cp := &chunkCheckpoint{Input: hashInput(text), Language: targetLang, Budget: budget, Translations: map[int]string{}}
+	if opts.Checkpoint != "" {
+		if saved, err := readCheckpoint(opts.Checkpoint); err != nil {
+			return "", "", err
+		} else if saved != nil && saved.Input == cp.Input && saved.Language == cp.Language && saved.Budget == cp.Budget {
+			cp.Translations = saved.Translations
+			t.logger.Debug("resuming from checkpoint", "file", opts.Checkpoint, "done", len(cp.Translations))
+		}
+	}
+
+	var pending []int
+	total := 0
+	for i, chunk := range chunks {
+		if chunk.text == "" {
+			continue
+		}
+		total++
+		if _, ok := cp.Translations[i]; !ok {
+			pending = append(pending, i)
+		}
+	}
+	done := total - len(pending)
+	if opts.Progress != nil {
+		opts.Progress(done, total)
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	sem := make(chan struct{}, max(t.config.Chunking.Concurrency, 1))
+	for _, i := range pending {
+		sem <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			t.logger.Debug("translating chunk", "chunk", i+1, "of", total, "tokens", EstimateTokens(chunks[i].text))
+			result, err := t.translateToLanguage(chunks[i].text, targetLang)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("chunk %d: %w", i+1, err)
+				}
+				return
+			}
+			cp.Translations[i] = result.Text
+			if opts.Checkpoint != "" {
+				if err := writeCheckpoint(opts.Checkpoint, cp); err != nil && firstErr == nil {
+					firstErr = err
+				}
+			}
+			done++
+			if opts.Progress != nil {
+				opts.Progress(done, total)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return "", "", firstErr
+	}
+
+	var b strings.Builder
+	for i, chunk := range chunks {
+		sep := chunk.sep
+		if sep == "" && i > 0 && chunk.text != "" && !isCJKLanguage(targetLang) {
+			// Chunks cut from unspaced CJK text need a space in other scripts
+			sep = " "
+		}
+		b.WriteString(sep)
+		b.WriteString(cp.Translations[i])
+	}
+
+	if opts.Checkpoint != "" {
+		if err := os.Remove(opts.Checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", "", fmt.Errorf("failed to remove checkpoint: %w", err)
+		}
+	}
+	return b.String(), targetLang, nil
+}
+
+// defaultChunkBudget is the token budget of a chunk when the response
+// length is not limited, half the default api.max_tokens
+const defaultChunkBudget = 500
+
+// chunkBudget returns the token budget of a chunk; by default half the
+// response limit, leaving room for translations longer than their source
+func (t *Translator) chunkBudget() int {
+	if t.config.Chunking.TokenBudget > 0 {
+		return t.config.Chunking.TokenBudget
+	}
+	if t.config.API.MaxTokens <= 0 {
+		return defaultChunkBudget
+	}
+	return max(t.config.API.MaxTokens/2, 1)
+}
+
+// isCJKLanguage reports whether lang is written without spaces between words
+func isCJKLanguage(lang string) bool {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	switch base {
+	case "zh", "ja", "ko":
+		return true
+	}
+	return false
+}
+
+// hashInput identifies the input of a checkpoint
+func hashInput(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// readCheckpoint reads a checkpoint file; a missing file is not an error
+func readCheckpoint(path string) (*chunkCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp chunkCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// writeCheckpoint replaces the checkpoint file atomically
+func writeCheckpoint(path string, cp *chunkCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path through a temporary file, so an
+// interrupted write never leaves it truncated
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -3,41 +3,184 @@ package src
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
 // Client represents an LLM API client
 type Client struct {
-	config *Config
-	client *http.Client
+	config  *Config
+	client  *http.Client
+	limiter *RateLimiter
+
+	// err is returned by every request when the transport could not be built
+	err error
 }
 
 // NewClient creates a new API client
 func NewClient(cfg *Config) *Client {
+	transport, err := newTransport(cfg.API)
 	return &Client{
 		config: cfg,
 		client: &http.Client{
-			Timeout: time.Duration(cfg.API.Timeout) * time.Second,
+			Timeout:   time.Duration(cfg.API.Timeout) * time.Second,
+			Transport: transport,
 		},
+		limiter: rateLimiterFor(cfg, cfg.API.Endpoint, cfg.API.Model),
+		err:     err,
+	}
+}
+
+// Name identifies the backend in output and logs: the configured name or
+// model@host
+func (c *Client) Name() string {
+	if c.config.API.Name != "" {
+		return c.config.API.Name
+	}
+	host := c.config.API.Endpoint
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	model := c.config.API.Model
+	if model == "" {
+		model = c.config.API.Deployment
+	}
+	return model + "@" + host
+}
+
+// APIError is returned when the API answers with a non-200 status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// retryableError marks an error another backend may not run into, such as a
+// model that does not accept images or a response cut off while reading
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (e *retryableError) Unwrap() error { return e.err }
+
+// IsRetryable reports whether err is worth retrying on another backend:
+// network errors, timeouts, rate limiting, server errors and errors marked
+// as retryable
+func IsRetryable(err error) bool {
+	var retryable *retryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return apiErr.StatusCode >= 500
 	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
 }
 
 // ChatRequest represents an OpenAI-compatible chat completion request
 type ChatRequest struct {
-	Model       string    `json:"model"`
+	Model       string    `json:"model,omitempty"`
 	Messages    []Message `json:"messages"`
 	Temperature float64   `json:"temperature"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
+	TopP        float64   `json:"top_p,omitempty"`
+	Seed        *int      `json:"seed,omitempty"`
+	N           int       `json:"n,omitempty"`
+
+	// MaxCompletionTokens replaces MaxTokens for reasoning models
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
 }
 
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+
+	// ReasoningContent is the reasoning some models return next to the
+	// answer; it is dropped from responses
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// Content is the content of a message: plain text, or text and image parts
+// for vision models. Text holds the plain text, or the text parts joined.
+type Content struct {
+	Text  string
+	Parts []ContentPart
+}
+
+// ContentPart is a text or image part of a multimodal message
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL is an image given by URL or as a base64 data URL
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// TextContent returns plain text content
+func TextContent(text string) Content {
+	return Content{Text: text}
+}
+
+// PartsContent returns multimodal content made of parts
+func PartsContent(parts ...ContentPart) Content {
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return Content{Text: strings.Join(texts, "\n"), Parts: parts}
+}
+
+// MarshalJSON encodes plain text as a string and multimodal content as a
+// list of parts
+func (c Content) MarshalJSON() ([]byte, error) {
+	if len(c.Parts) > 0 {
+		return json.Marshal(c.Parts)
+	}
+	return json.Marshal(c.Text)
+}
+
+// UnmarshalJSON accepts a string, a list of parts or null
+func (c *Content) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var parts []ContentPart
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return err
+		}
+		*c = PartsContent(parts...)
+		return nil
+	}
+	var text *string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*c = Content{}
+	if text != nil {
+		c.Text = *text
+	}
+	return nil
 }
 
 // ChatResponse represents an OpenAI-compatible chat completion response
@@ -66,46 +209,120 @@ type Usage struct {
 
 // Translate translates text to the specified language
 func (c *Client) Translate(text, targetLanguage string) (string, error) {
-	prompt := c.buildPrompt(text, targetLanguage)
+	return c.translate(text, targetLanguage, nil, c.scrubber())
+}
+
+// translate translates text with optional context messages placed before the
+// prompt, scrubbing the request with s
+func (c *Client) translate(text, targetLanguage string, history []Message, s *Scrubber) (string, error) {
+	chatResp, err := c.complete(c.translateRequest(text, targetLanguage, history), s)
+	if err != nil {
+		return "", err
+	}
+
+	translation := strings.TrimSpace(chatResp.Choices[0].Message.Content.Text)
+	return translation, nil
+}
+
+// translateRequest builds the chat request translating text
+func (c *Client) translateRequest(text, targetLanguage string, history []Message) ChatRequest {
+	messages := c.buildMessages(text, targetLanguage)
+	if len(history) > 0 {
+		last := messages[len(messages)-1]
+		messages = append(append(messages[:len(messages)-1:len(messages)-1], history...), last)
+	}
+	return c.newRequest(messages)
+}
+
+// scrubber returns a new scrubber if scrubbing is enabled, nil otherwise
+func (c *Client) scrubber() *Scrubber {
+	if !c.config.Scrub.Enabled {
+		return nil
+	}
+	return NewScrubber(c.config.Scrub.Kinds)
+}
+
+// Chat sends a chat completion request with the configured model settings
+func (c *Client) Chat(messages []Message) (*ChatResponse, error) {
+	return c.Complete(c.newRequest(messages))
+}
 
-	request := ChatRequest{
-		Model:       c.config.API.Model,
+// newRequest builds a chat request with the configured model settings
+func (c *Client) newRequest(messages []Message) ChatRequest {
+	return ChatRequest{
+		Model:       c.requestModel(),
 		Temperature: c.config.API.Temperature,
 		MaxTokens:   c.config.API.MaxTokens,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+		TopP:        c.config.API.TopP,
+		Seed:        c.config.API.Seed,
+		Messages:    messages,
 	}
+}
+
+// Complete sends a chat completion request. With scrubbing enabled the
+// sensitive values in every message are replaced by placeholders before
+// sending and restored in the response.
+func (c *Client) Complete(request ChatRequest) (*ChatResponse, error) {
+	return c.complete(request, c.scrubber())
+}
+
+// complete is Complete scrubbing with s, which the requests of one document
+// share so that a value keeps its placeholder across them
+func (c *Client) complete(request ChatRequest, s *Scrubber) (*ChatResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	request = c.profile().adapt(request)
+	request.Messages = s.scrubMessages(request.Messages)
 
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.config.API.Endpoint, bytes.NewBuffer(jsonData))
+	endpoint, err := c.endpointURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.API.Key)
+	c.setAuth(req.Header)
+	for k, v := range c.config.API.Headers {
+		req.Header.Set(k, v)
+	}
 
 	if c.config.Advanced.Debug {
 		fmt.Printf("[DEBUG] API Request: %s\n", string(jsonData))
 	}
 
+	estimated := 0
+	if c.limiter != nil {
+		estimated = estimateRequestTokens(request)
+		if waited := c.limiter.Wait(estimated); waited > 0 && c.config.Advanced.Debug {
+			fmt.Printf("[DEBUG] Rate limited, waited %s\n", waited.Round(time.Millisecond))
+		}
+	}
+
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
+		if c.limiter != nil {
+			c.limiter.Release(estimated)
+		}
+		return nil, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	// A response cut off by a timeout or reset is worth retrying elsewhere
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		if c.limiter != nil {
+			c.limiter.Release(estimated)
+		}
+		return nil, &retryableError{fmt.Errorf("failed to read response: %w", err)}
 	}
 
 	if c.config.Advanced.Debug {
@@ -113,37 +330,45 @@ func (c *Client) Translate(text, targetLanguage string) (string, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		if c.limiter != nil {
+			c.limiter.Release(estimated)
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
+	// A response that cannot be parsed was still generated, so its
+	// reservation is kept
 	var chatResp ChatResponse
 	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if c.limiter != nil {
+		c.limiter.Reconcile(estimated, chatResp.Usage.TotalTokens)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return nil, fmt.Errorf("no response choices returned")
+	}
+	for i := range chatResp.Choices {
+		choice := &chatResp.Choices[i]
+		stripReasoning(&choice.Message)
+		choice.Message.Content = TextContent(s.Restore(choice.Message.Content.Text))
+		if choice.Message.Content.Text == "" && choice.FinishReason == "length" {
+			return nil, fmt.Errorf("response cut off before the answer, increase api.max_tokens to leave room for reasoning")
+		}
 	}
 
-	translation := strings.TrimSpace(chatResp.Choices[0].Message.Content)
-	return translation, nil
+	return &chatResp, nil
 }
 
 // buildPrompt builds the translation prompt
 func (c *Client) buildPrompt(text, targetLanguage string) string {
 	template := c.config.Advanced.PromptTemplate
 	if template == "" {
-		template = `You are a professional translator. Translate the following text to {language}.
-Only return the translated text without any explanation or additional content.
-
-Text: {input_text}`
+		template = defaultPromptTemplate
 	}
-
-	langName := getLanguageName(targetLanguage)
-	prompt := strings.ReplaceAll(template, "{language}", langName)
-	prompt = strings.ReplaceAll(prompt, "{input_text}", text)
-
-	return prompt
+	return c.renderTemplate(template, text, targetLanguage)
 }
 
 // getLanguageName returns the full name of a language code
@@ -0,0 +1,144 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestServer starts an OpenAI-compatible server that answers each request
+// with reply and records the decoded requests
+func newTestServer(t *testing.T, reply func(ChatRequest) string) (*httptest.Server, *[]ChatRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []ChatRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []Choice{{Message: Message{Role: "assistant", Content: TextContent(reply(req))}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+// testConfig returns a valid config pointing at endpoint
+func testConfig(endpoint string) *Config {
+	cfg := DefaultConfig()
+	cfg.API.Endpoint = endpoint
+	cfg.API.Key = "test"
+	return cfg
+}
+
+func TestClientTranslate(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "  你好  " })
+	got, err := NewClient(testConfig(srv.URL)).Translate("hello", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "你好" {
+		t.Errorf("got %q, want %q", got, "你好")
+	}
+	if len(*requests) != 1 || !strings.Contains((*requests)[0].Messages[0].Content.Text, "Chinese") {
+		t.Errorf("unexpected requests: %+v", *requests)
+	}
+}
+
+func TestConversationCarriesContext(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		last := req.Messages[len(req.Messages)-1].Content.Text
+		if strings.HasPrefix(last, "You maintain a running summary") {
+			return "Alice is the narrator."
+		}
+		return "translated"
+	})
+	cfg := testConfig(srv.URL)
+	cfg.ContextWindow = ContextWindowConfig{Enabled: true, TokenBudget: 1000, Summary: true}
+
+	conv := NewClient(cfg).NewConversation("zh")
+	for _, chunk := range []string{"Alice went home.", "She was tired."} {
+		if _, err := conv.Translate(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// translate, summarize, translate, summarize
+	if len(*requests) != 4 {
+		t.Fatalf("got %d requests, want 4", len(*requests))
+	}
+	second := (*requests)[2].Messages
+	var sawSummary, sawPrevious bool
+	for _, m := range second {
+		sawSummary = sawSummary || strings.Contains(m.Content.Text, "Alice is the narrator.")
+		sawPrevious = sawPrevious || (m.Role == "assistant" && m.Content.Text == "translated")
+	}
+	if !sawSummary || !sawPrevious {
+		t.Errorf("second chunk is missing context: %+v", second)
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	got := splitParagraphs("one\nline\n\n  \n two \n \nthree")
+	want := []string{"one\nline", "two", "three"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAlternativesRepeatsSamplingAndRanks(t *testing.T) {
+	answers := []string{
+		`{"translation": "祝你好运", "note": "common"}`,
+		"```json\n{\"translation\": \"祝演出成功\", \"note\": \"before a show\"}\n```",
+		`{"translation": "祝你好运", "note": "common"}`,
+	}
+	var i int
+	// The server ignores n and always returns a single choice
+	srv, requests := newTestServer(t, func(ChatRequest) string {
+		answer := answers[i%len(answers)]
+		i++
+		return answer
+	})
+
+	variants, err := NewClient(testConfig(srv.URL)).Alternatives("break a leg", "zh", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 2 || variants[0].Text != "祝你好运" || variants[1].Note != "before a show" {
+		t.Errorf("unexpected variants: %+v", variants)
+	}
+	if (*requests)[0].N != 2 || (*requests)[1].N != 1 {
+		t.Errorf("unexpected n in requests: %d, %d", (*requests)[0].N, (*requests)[1].N)
+	}
+}
+
+func TestClientSamplingParameters(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "ok" })
+	cfg := testConfig(srv.URL)
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	seed := 0
+	cfg.API.TopP, cfg.API.Seed = 0.5, &seed
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+
+	if req := (*requests)[0]; req.TopP != 0 || req.Seed != nil {
+		t.Errorf("unset top_p and seed were sent: %+v", req)
+	}
+	if req := (*requests)[1]; req.TopP != 0.5 || req.Seed == nil || *req.Seed != 0 {
+		t.Errorf("top_p and seed not sent: %+v", req)
+	}
+}
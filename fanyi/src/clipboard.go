@@ -0,0 +1,197 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Clipboard reads and writes the system clipboard
+type Clipboard interface {
+	Read() (string, error)
+	Write(text string) error
+}
+
+// commandClipboard adapts clipboard command line tools such as xclip
+type commandClipboard struct {
+	read  []string
+	write []string
+}
+
+// clipboardCommands are the supported tools in order of preference
+var clipboardCommands = []struct {
+	wayland bool
+	goos    string
+	read    []string
+	write   []string
+}{
+	{goos: "darwin", read: []string{"pbpaste"}, write: []string{"pbcopy"}},
+	{wayland: true, read: []string{"wl-paste", "--no-newline"}, write: []string{"wl-copy"}},
+	{read: []string{"xclip", "-selection", "clipboard", "-o"}, write: []string{"xclip", "-selection", "clipboard", "-i"}},
+	{read: []string{"xsel", "--clipboard", "--output"}, write: []string{"xsel", "--clipboard", "--input"}},
+}
+
+// DetectClipboard returns an adapter for the first clipboard tool available
+// on this system: pbpaste/pbcopy, wl-paste/wl-copy, xclip or xsel
+func DetectClipboard() (Clipboard, error) {
+	wayland := os.Getenv("WAYLAND_DISPLAY") != ""
+	var tried []string
+	for _, cmd := range clipboardCommands {
+		if (cmd.goos != "" && cmd.goos != runtime.GOOS) || (cmd.wayland && !wayland) {
+			continue
+		}
+		tried = append(tried, cmd.read[0])
+		if _, err := exec.LookPath(cmd.read[0]); err == nil {
+			return &commandClipboard{read: cmd.read, write: cmd.write}, nil
+		}
+	}
+	return nil, fmt.Errorf("no clipboard tool found (tried %s)", strings.Join(tried, ", "))
+}
+
+// Read returns the clipboard content
+func (c *commandClipboard) Read() (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.read[0], c.read[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", c.read[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// Write replaces the clipboard content
+func (c *commandClipboard) Write(text string) error {
+	cmd := exec.Command(c.write[0], c.write[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", c.write[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ClipboardWatcher polls a clipboard and hands over new content once it has
+// been stable for the debounce period
+type ClipboardWatcher struct {
+	clipboard Clipboard
+	interval  time.Duration
+	debounce  time.Duration
+	writeBack bool
+	logger    *slog.Logger
+}
+
+// NewClipboardWatcher creates a watcher using the clipboard configuration
+func (t *Translator) NewClipboardWatcher(cb Clipboard) *ClipboardWatcher {
+	return &ClipboardWatcher{
+		clipboard: cb,
+		interval:  time.Duration(t.config.Clipboard.Interval) * time.Millisecond,
+		debounce:  time.Duration(t.config.Clipboard.Debounce) * time.Millisecond,
+		writeBack: t.config.Clipboard.WriteBack,
+		logger:    t.logger,
+	}
+}
+
+// Watch calls handle for each new clipboard text until ctx is cancelled.
+// Content present when watching starts, repeats of the last input and the
+// text written back by the watcher itself are skipped. If write-back is
+// enabled, the text returned by handle replaces the clipboard content.
+func (w *ClipboardWatcher) Watch(ctx context.Context, handle func(text string) (string, error)) error {
+	s := w.start()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			w.poll(s, now, handle)
+		}
+	}
+}
+
+// watchState is what a watcher remembers between polls
+type watchState struct {
+	seen, pending         string
+	lastInput, lastOutput string
+	changedAt             time.Time
+}
+
+// start reads the content present when watching starts, which is skipped
+func (w *ClipboardWatcher) start() *watchState {
+	seen, err := w.clipboard.Read()
+	if err != nil {
+		w.logger.Debug("clipboard read failed", "err", err)
+	}
+	return &watchState{seen: seen, lastInput: strings.TrimSpace(seen)}
+}
+
+// poll reads the clipboard at time now and hands over content that has not
+// changed for the debounce period
+func (w *ClipboardWatcher) poll(s *watchState, now time.Time, handle func(text string) (string, error)) {
+	content, err := w.clipboard.Read()
+	if err != nil {
+		w.logger.Debug("clipboard read failed", "err", err)
+		return
+	}
+	if content != s.seen {
+		s.seen, s.pending, s.changedAt = content, content, now
+	}
+	if s.pending == "" || now.Sub(s.changedAt) < w.debounce {
+		return
+	}
+
+	text := strings.TrimSpace(s.pending)
+	s.pending = ""
+	if text == "" || text == s.lastInput || text == s.lastOutput {
+		return
+	}
+	s.lastInput = text
+
+	out, err := handle(text)
+	if err != nil {
+		w.logger.Error("failed to translate clipboard", "err", err)
+		return
+	}
+	if w.writeBack && out != "" {
+		if err := w.clipboard.Write(out); err != nil {
+			w.logger.Error("failed to write clipboard", "err", err)
+			return
+		}
+		s.lastOutput = strings.TrimSpace(out)
+	}
+}
+
+// WatchClipboard translates new clipboard content into targetLang, or the
+// priority languages if it is empty, until ctx is cancelled, passing each
+// result to emit. With write-back enabled the translation into the first
+// language other than the source replaces the clipboard content.
+func (t *Translator) WatchClipboard(ctx context.Context, cb Clipboard, targetLang string, emit func(Output)) error {
+	return t.NewClipboardWatcher(cb).Watch(ctx, t.clipboardHandler(targetLang, emit))
+}
+
+// clipboardHandler translates clipboard text for WatchClipboard, returning
+// the text to write back
+func (t *Translator) clipboardHandler(targetLang string, emit func(Output)) func(text string) (string, error) {
+	return func(text string) (string, error) {
+		result, err := t.Translate(text, targetLang)
+		if err != nil {
+			return "", err
+		}
+		emit(result)
+
+		source := t.sourceLanguage(text)
+		for _, translation := range result.Translations {
+			if translation.Language != source {
+				return translation.Text, nil
+			}
+		}
+		return result.Translations[0].Text, nil
+	}
+}
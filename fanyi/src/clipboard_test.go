@@ -0,0 +1,130 @@
+package src
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClipboard is an in-memory clipboard for tests
+type fakeClipboard struct {
+	mu      sync.Mutex
+	content string
+	writes  []string
+}
+
+func (f *fakeClipboard) Read() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.content, nil
+}
+
+func (f *fakeClipboard) Write(text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content = text
+	f.writes = append(f.writes, text)
+	return nil
+}
+
+func (f *fakeClipboard) set(text string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.content = text
+}
+
+func TestClipboardWatcher(t *testing.T) {
+	cb := &fakeClipboard{content: "already there"}
+	w := &ClipboardWatcher{
+		clipboard: cb,
+		debounce:  10 * time.Millisecond,
+		writeBack: true,
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	var inputs []string
+	handle := func(text string) (string, error) {
+		inputs = append(inputs, text)
+		return "translated: " + text, nil
+	}
+
+	s := w.start()
+	start := time.Now()
+	poll := func(ms int) { w.poll(s, start.Add(time.Duration(ms)*time.Millisecond), handle) }
+
+	// Content present at the start is skipped
+	poll(20)
+
+	// Intermediate content replaced within the debounce period is skipped
+	cb.set("hel")
+	poll(30)
+	cb.set("hello ")
+	poll(35)
+	poll(40)
+	poll(45)
+
+	// The text written back and re-copying the same text are skipped
+	poll(60)
+	poll(75)
+	cb.set("hello")
+	poll(80)
+	poll(100)
+
+	cb.set("world")
+	poll(120)
+	poll(130)
+
+	if len(inputs) != 2 || inputs[0] != "hello" || inputs[1] != "world" {
+		t.Errorf("handled %q, want [hello world]", inputs)
+	}
+	if len(cb.writes) != 2 || cb.writes[1] != "translated: world" {
+		t.Errorf("clipboard writes %q", cb.writes)
+	}
+}
+
+func TestClipboardWatcherStops(t *testing.T) {
+	w := &ClipboardWatcher{
+		clipboard: &fakeClipboard{},
+		interval:  time.Hour,
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.Watch(ctx, func(text string) (string, error) {
+		t.Errorf("unexpected text %q", text)
+		return "", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchClipboardTarget(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		if !strings.Contains(req.Messages[0].Content.Text, "Japanese") {
+			t.Errorf("unexpected prompt %q", req.Messages[0].Content.Text)
+		}
+		return "こんにちは"
+	})
+	trans, _ := NewTranslator(testConfig(srv.URL))
+
+	var results []Output
+	out, err := trans.clipboardHandler("ja", func(result Output) { results = append(results, result) })("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "こんにちは" {
+		t.Errorf("write back %q", out)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if result := results[0].(*Result); len(result.Translations) != 1 || result.Translations[0].Language != "ja" {
+		t.Errorf("got translations %+v", result.Translations)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("got %d requests, want 1", len(*requests))
+	}
+}
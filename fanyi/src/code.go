@@ -0,0 +1,365 @@
+package src
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// translateDirective marks the string literals on its line, or on the line
+// below when it stands alone, for translation by TranslateGoSource. On a
+// line of its own it also marks the comments around it.
+const translateDirective = "//fanyi:translate"
+
+// directiveRe matches comments that are read by tools rather than people,
+// such as //go:generate, //nolint:errcheck and // +build
+var directiveRe = regexp.MustCompile(`^//(?:[a-z0-9]+:\S|line |export |extern | \+build)`)
+
+// formatVerbRe matches fmt verbs, which must survive the translation of a
+// string literal
+var formatVerbRe = regexp.MustCompile(`%[-+#0]*(?:\[\d+\])?(?:\d+|\*)?(?:\.(?:\d+|\*))?[a-zA-Z%]`)
+
+// CodeResult counts the translated pieces of a source file
+type CodeResult struct {
+	Comments  int  `json:"comments"`
+	Strings   int  `json:"strings"`
+	Generated bool `json:"generated,omitempty"`
+}
+
+// codeEdit replaces the source bytes [start, end) with text
+type codeEdit struct {
+	start, end int
+	text       string
+}
+
+// codePiece is a comment or string literal to translate
+type codePiece struct {
+	text    string
+	literal bool
+	edit    func(translation string) (codeEdit, bool)
+}
+
+// TranslateGoSource translates the comments and string literals of a Go
+// source file marked with //fanyi:translate into targetLang; with
+// allComments every comment is translated, marked or not. Directives,
+// license headers and the cgo preamble are kept, as are pieces already
+// written in the target language. Generated files are returned unchanged.
+// Without targetLang the file is translated to the first priority language
+// that differs from its own.
+func (t *Translator) TranslateGoSource(filename string, src []byte, targetLang string, allComments bool) ([]byte, *CodeResult, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	result := &CodeResult{}
+	if ast.IsGenerated(file) {
+		result.Generated = true
+		return src, result, nil
+	}
+
+	pieces := collectComments(fset, file, src, allComments)
+	pieces = append(pieces, collectLiterals(fset, file, src)...)
+	if len(pieces) == 0 {
+		return src, result, nil
+	}
+
+	lang := targetLang
+	if lang == "" {
+		var b strings.Builder
+		for _, p := range pieces {
+			b.WriteString(p.text + "\n")
+		}
+		lang = t.defaultTarget(b.String())
+	}
+
+	var edits []codeEdit
+	for _, p := range pieces {
+		if writtenIn(p.text, lang) {
+			continue
+		}
+		translation, err := t.translateToLanguage(p.text, lang)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		edit, ok := p.edit(strings.TrimSpace(translation.Text))
+		if !ok {
+			t.logger.Warn("kept string literal", "text", p.text, "reason", "format verbs changed")
+			continue
+		}
+		edits = append(edits, edit)
+		if p.literal {
+			result.Strings++
+		} else {
+			result.Comments++
+		}
+	}
+	if len(edits) == 0 {
+		return src, result, nil
+	}
+
+	// Apply the edits back to front so earlier offsets stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := slices.Clone(src)
+	for _, e := range edits {
+		out = slices.Concat(out[:e.start], []byte(e.text), out[e.end:])
+	}
+	formatted, err := format.Source(out)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to format %s: %w", filename, err)
+	}
+	return formatted, result, nil
+}
+
+// writtenIn reports whether text is already in lang. Text for a Latin
+// script target must not contain any other script, since comments in other
+// languages often start with Latin identifiers.
+func writtenIn(text, lang string) bool {
+	if detectLanguage(text) != lang {
+		return false
+	}
+	if lang != "en" {
+		return true
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectComments returns the comments to translate: those in a group with
+// the translate directive on a line of its own, or all of them with all.
+// Consecutive line comments are translated as one passage, up to any
+// directive among them.
+func collectComments(fset *token.FileSet, file *ast.File, src []byte, all bool) []codePiece {
+	skip := map[*ast.CommentGroup]bool{}
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"C"` {
+			skip[imp.Doc] = true
+			for _, decl := range file.Decls {
+				if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && slices.Contains(gen.Specs, ast.Spec(imp)) {
+					skip[gen.Doc] = true
+				}
+			}
+		}
+	}
+
+	var pieces []codePiece
+	for _, group := range file.Comments {
+		if skip[group] || isLicense(fset, file, group) || !all && !markedGroup(fset, src, group) {
+			continue
+		}
+		var run []*ast.Comment
+		flush := func() {
+			// Blank // lines around a passage stay as they are
+			for len(run) > 0 && strings.TrimSpace(run[0].Text) == "//" {
+				run = run[1:]
+			}
+			for len(run) > 0 && strings.TrimSpace(run[len(run)-1].Text) == "//" {
+				run = run[:len(run)-1]
+			}
+			if len(run) > 0 {
+				pieces = append(pieces, lineCommentPiece(fset, src, run))
+			}
+			run = nil
+		}
+		for _, comment := range group.List {
+			switch {
+			case directiveRe.MatchString(comment.Text):
+				flush()
+			case strings.HasPrefix(comment.Text, "/*"):
+				flush()
+				if p, ok := blockCommentPiece(fset, comment); ok {
+					pieces = append(pieces, p)
+				}
+			default:
+				// A comment after code starts its own passage
+				if _, trailing := lineIndent(fset, src, comment.Pos()); trailing || len(run) > 0 && runTrailing(fset, src, run) {
+					flush()
+				}
+				run = append(run, comment)
+			}
+		}
+		flush()
+	}
+	return pieces
+}
+
+// markedGroup reports whether group has the translate directive on a line of
+// its own. A directive after code marks only the string literals on its line.
+func markedGroup(fset *token.FileSet, src []byte, group *ast.CommentGroup) bool {
+	for _, comment := range group.List {
+		if _, trailing := lineIndent(fset, src, comment.Pos()); isTranslateDirective(comment.Text) && !trailing {
+			return true
+		}
+	}
+	return false
+}
+
+// isTranslateDirective reports whether comment is the translate directive
+func isTranslateDirective(comment string) bool {
+	return comment == translateDirective || strings.HasPrefix(comment, translateDirective+" ")
+}
+
+// isLicense reports whether group is a copyright or license header above
+// the package clause
+func isLicense(fset *token.FileSet, file *ast.File, group *ast.CommentGroup) bool {
+	if group.Pos() > file.Package || group == file.Doc {
+		return false
+	}
+	text := group.Text()
+	return strings.HasPrefix(text, "Copyright") || strings.Contains(text, "SPDX-License-Identifier")
+}
+
+// lineCommentPiece joins consecutive // comments into one passage; the
+// translation is written back as // lines with the indentation of the first
+// comment. A comment at the end of a code line stays on one line.
+func lineCommentPiece(fset *token.FileSet, src []byte, run []*ast.Comment) codePiece {
+	lines := make([]string, len(run))
+	for i, comment := range run {
+		text := strings.TrimPrefix(comment.Text, "//")
+		lines[i] = strings.TrimPrefix(text, " ")
+	}
+
+	start := fset.Position(run[0].Pos()).Offset
+	end := fset.Position(run[len(run)-1].End()).Offset
+	indent, trailing := lineIndent(fset, src, run[0].Pos())
+
+	return codePiece{
+		text: strings.Join(lines, "\n"),
+		edit: func(translation string) (codeEdit, bool) {
+			if trailing {
+				return codeEdit{start, end, "// " + strings.Join(strings.Fields(translation), " ")}, true
+			}
+			out := strings.Split(translation, "\n")
+			for i, line := range out {
+				if line = strings.TrimRight(line, " \t"); line == "" {
+					out[i] = "//"
+				} else {
+					out[i] = "// " + line
+				}
+			}
+			return codeEdit{start, end, strings.Join(out, "\n"+indent)}, true
+		},
+	}
+}
+
+// lineIndent returns the text before pos on its line; trailing reports
+// whether that is code rather than indentation
+func lineIndent(fset *token.FileSet, src []byte, pos token.Pos) (indent string, trailing bool) {
+	p := fset.Position(pos)
+	indent = string(src[p.Offset-(p.Column-1) : p.Offset])
+	return indent, strings.TrimSpace(indent) != ""
+}
+
+func runTrailing(fset *token.FileSet, src []byte, run []*ast.Comment) bool {
+	_, trailing := lineIndent(fset, src, run[0].Pos())
+	return trailing
+}
+
+// blockCommentPiece translates the text of a /* */ comment, keeping the
+// whitespace around it
+func blockCommentPiece(fset *token.FileSet, comment *ast.Comment) (codePiece, bool) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(comment.Text, "/*"), "*/")
+	core := strings.TrimSpace(inner)
+	if core == "" {
+		return codePiece{}, false
+	}
+	lead := inner[:strings.Index(inner, core)]
+	tail := inner[len(lead)+len(core):]
+	start, end := fset.Position(comment.Pos()).Offset, fset.Position(comment.End()).Offset
+
+	return codePiece{
+		text: core,
+		edit: func(translation string) (codeEdit, bool) {
+			translation = strings.ReplaceAll(translation, "*/", "* /")
+			return codeEdit{start, end, "/*" + lead + translation + tail + "*/"}, true
+		},
+	}, true
+}
+
+// collectLiterals returns the string literals on lines marked with the
+// translate directive, excluding struct tags and import paths
+func collectLiterals(fset *token.FileSet, file *ast.File, src []byte) []codePiece {
+	marked := map[int]bool{}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !isTranslateDirective(comment.Text) {
+				continue
+			}
+			line := fset.Position(comment.Pos()).Line
+			if _, trailing := lineIndent(fset, src, comment.Pos()); trailing {
+				marked[line] = true
+			} else {
+				marked[line+1] = true
+			}
+		}
+	}
+	if len(marked) == 0 {
+		return nil
+	}
+
+	skip := map[*ast.BasicLit]bool{}
+	for _, imp := range file.Imports {
+		skip[imp.Path] = true
+	}
+	var pieces []codePiece
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			if n.Tag != nil {
+				skip[n.Tag] = true
+			}
+		case *ast.BasicLit:
+			if n.Kind != token.STRING || skip[n] || !marked[fset.Position(n.Pos()).Line] {
+				return true
+			}
+			if p, ok := literalPiece(fset, n); ok {
+				pieces = append(pieces, p)
+			}
+		}
+		return true
+	})
+	return pieces
+}
+
+// literalPiece translates the value of a string literal. The translation is
+// kept only if it has the same fmt verbs in the same order.
+func literalPiece(fset *token.FileSet, lit *ast.BasicLit) (codePiece, bool) {
+	value, err := strconv.Unquote(lit.Value)
+	core := strings.TrimSpace(value)
+	if err != nil || core == "" {
+		return codePiece{}, false
+	}
+	// Keep the surrounding whitespace, such as a trailing newline
+	lead := value[:len(value)-len(strings.TrimLeftFunc(value, unicode.IsSpace))]
+	tail := value[len(strings.TrimRightFunc(value, unicode.IsSpace)):]
+	raw := strings.HasPrefix(lit.Value, "`")
+	start, end := fset.Position(lit.Pos()).Offset, fset.Position(lit.End()).Offset
+
+	return codePiece{
+		text:    core,
+		literal: true,
+		edit: func(translation string) (codeEdit, bool) {
+			if !slices.Equal(formatVerbRe.FindAllString(core, -1), formatVerbRe.FindAllString(translation, -1)) {
+				return codeEdit{}, false
+			}
+			translation = lead + translation + tail
+			quoted := strconv.Quote(translation)
+			if raw && !strings.Contains(translation, "`") && !strings.Contains(translation, "\r") {
+				quoted = "`" + translation + "`"
+			}
+			return codeEdit{start, end, quoted}, true
+		},
+	}, true
+}
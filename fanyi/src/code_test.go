@@ -0,0 +1,199 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+// codeReplies are the translations returned by the test server
+var codeReplies = map[string]string{
+	"Package demo 演示翻译。":    "Package demo demonstrates translation.",
+	"Mode 表示模式":             "Mode is a mode",
+	"Greet 打招呼。\n\n它返回问候语。": "Greet says hello.\n\nIt returns a greeting.",
+	"计数器":   "counts\nthe calls",
+	"临时代码":  "temporary code",
+	"你好，%s": "Hello, %s",
+	"失败：%d": "failed",
+}
+
+const codeInput = `// Copyright 2026 示例公司
+// SPDX-License-Identifier: MIT
+
+// Package demo 演示翻译。
+package demo
+
+import "fmt"
+
+//go:generate stringer -type=Mode
+
+// Mode 表示模式
+type Mode int
+
+type Config struct {
+	Name string ` + "`json:\"名字\"`" + ` //fanyi:translate
+}
+
+// Greet 打招呼。
+//
+// 它返回问候语。
+//
+//go:noinline
+func Greet(name string) string {
+	x := 1 // 计数器
+	_ = x
+	/* 临时代码 */
+	//fanyi:translate
+	return fmt.Sprintf("你好，%s\n", name)
+}
+
+func Fail() error {
+	return fmt.Errorf("失败：%d", 1) //fanyi:translate
+}
+
+var kept = "不翻译"
+
+// Already in English
+`
+
+const codeOutput = `// Copyright 2026 示例公司
+// SPDX-License-Identifier: MIT
+
+// Package demo demonstrates translation.
+package demo
+
+import "fmt"
+
+//go:generate stringer -type=Mode
+
+// Mode is a mode
+type Mode int
+
+type Config struct {
+	Name string ` + "`json:\"名字\"`" + ` //fanyi:translate
+}
+
+// Greet says hello.
+//
+// It returns a greeting.
+//
+//go:noinline
+func Greet(name string) string {
+	x := 1 // counts the calls
+	_ = x
+	/* temporary code */
+	//fanyi:translate
+	return fmt.Sprintf("Hello, %s\n", name)
+}
+
+func Fail() error {
+	return fmt.Errorf("失败：%d", 1) //fanyi:translate
+}
+
+var kept = "不翻译"
+
+// Already in English
+`
+
+func TestTranslateGoSource(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		text := prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):]
+		if reply, ok := codeReplies[text]; ok {
+			return reply
+		}
+		t.Errorf("unexpected text %q", text)
+		return text
+	})
+	trans, _ := NewTranslator(testConfig(srv.URL))
+
+	output, result, err := trans.TranslateGoSource("demo.go", []byte(codeInput), "en", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != codeOutput {
+		t.Errorf("got\n%s", output)
+	}
+	if result.Comments != 5 || result.Strings != 1 {
+		t.Errorf("got result %+v", result)
+	}
+	if len(*requests) != len(codeReplies) {
+		t.Errorf("got %d requests, want %d", len(*requests), len(codeReplies))
+	}
+}
+
+func TestTranslateGoSourceMarkedComments(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		text := prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):]
+		if reply, ok := codeReplies[text]; ok {
+			return reply
+		}
+		t.Errorf("unexpected text %q", text)
+		return text
+	})
+	trans, _ := NewTranslator(testConfig(srv.URL))
+
+	input := "package demo\n\n// Mode 表示模式\n//\n//fanyi:translate\ntype Mode int\n\n// 计数器\nvar n = 1\n\nvar s = \"你好，%s\" //fanyi:translate\n// 临时代码\n"
+	want := "package demo\n\n// Mode is a mode\n//\n//fanyi:translate\ntype Mode int\n\n// 计数器\nvar n = 1\n\nvar s = \"Hello, %s\" //fanyi:translate\n// 临时代码\n"
+	output, result, err := trans.TranslateGoSource("demo.go", []byte(input), "en", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != want {
+		t.Errorf("got\n%s", output)
+	}
+	if result.Comments != 1 || result.Strings != 1 || len(*requests) != 2 {
+		t.Errorf("got result %+v after %d requests", result, len(*requests))
+	}
+}
+
+func TestTranslateGoSourceSkipsGenerated(t *testing.T) {
+	trans, _ := NewTranslator(testConfig("http://127.0.0.1:0"))
+	input := "// Code generated by stringer. DO NOT EDIT.\n\npackage demo\n\n// 模式\ntype Mode int\n"
+	output, result, err := trans.TranslateGoSource("mode_string.go", []byte(input), "en", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Generated || string(output) != input {
+		t.Errorf("generated file changed: %+v\n%s", result, output)
+	}
+
+	if _, _, err := trans.TranslateGoSource("broken.go", []byte("package demo\nfunc {"), "en", true); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestTranslateCommitMessage(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		if text := prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):]; text != "修复登录超时\n\n会话过期后重新获取令牌。" {
+			t.Errorf("unexpected text %q", text)
+		}
+		return "Fix login timeout\n\nRefresh the token after the session expires."
+	})
+	trans, _ := NewTranslator(testConfig(srv.URL))
+
+	msg := "修复登录超时\n\n会话过期后重新获取令牌。\n\nSigned-off-by: Li Lei <li@example.com>\nFixes: #42\n" +
+		"# Please enter the commit message for your changes.\n#\n" +
+		scissorsLine + "\ndiff --git a/main.go b/main.go\n"
+	got, lang, err := trans.TranslateCommitMessage(msg, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Fix login timeout\n\nRefresh the token after the session expires.\n\n" +
+		"Signed-off-by: Li Lei <li@example.com>\nFixes: #42\n\n" +
+		"# Please enter the commit message for your changes.\n#\n" +
+		scissorsLine + "\ndiff --git a/main.go b/main.go\n"
+	if got != want || lang != "en" {
+		t.Errorf("got %s\n%s", lang, got)
+	}
+
+	for _, unchanged := range []string{"Fix the build\n", "# only comments\n"} {
+		if got, _, err := trans.TranslateCommitMessage(unchanged, "en"); err != nil || got != unchanged {
+			t.Errorf("%q changed to %q (%v)", unchanged, got, err)
+		}
+	}
+	if len(*requests) != 1 {
+		t.Errorf("got %d requests, want 1", len(*requests))
+	}
+}
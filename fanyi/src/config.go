@@ -12,39 +12,189 @@ import (
 
 // Config represents the complete configuration structure
 type Config struct {
-	API       APIConfig      `yaml:"api"`
-	Languages LanguageConfig `yaml:"languages"`
-	Advanced  AdvancedConfig `yaml:"advanced"`
+	API           APIConfig           `yaml:"api"`
+	Languages     LanguageConfig      `yaml:"languages"`
+	ContextWindow ContextWindowConfig `yaml:"context_window"`
+	Chunking      ChunkingConfig      `yaml:"chunking"`
+	Verify        VerifyConfig        `yaml:"verify"`
+	Cache         CacheConfig         `yaml:"cache"`
+	Server        ServerConfig        `yaml:"server"`
+	Clipboard     ClipboardConfig     `yaml:"clipboard"`
+	RateLimits    []RateLimitConfig   `yaml:"rate_limits"`
+	Hooks         []HookConfig        `yaml:"hooks"`
+	Scrub         ScrubConfig         `yaml:"scrub"`
+	History       HistoryConfig       `yaml:"history"`
+	Advanced      AdvancedConfig      `yaml:"advanced"`
+
+	// sources records where traced settings were set, see Resolved
+	sources map[string]string
 }
 
 // APIConfig represents API-related configuration
 type APIConfig struct {
-	Endpoint    string  `yaml:"endpoint"`
-	Key         string  `yaml:"key"`
-	Model       string  `yaml:"model"`
-	Timeout     int     `yaml:"timeout"`
-	MaxTokens   int     `yaml:"max_tokens"`
-	Temperature float64 `yaml:"temperature"`
+	Name        string          `yaml:"name"`
+	Endpoint    string          `yaml:"endpoint"`
+	Key         string          `yaml:"key"`
+	Model       string          `yaml:"model"`
+	Timeout     int             `yaml:"timeout"`
+	MaxTokens   int             `yaml:"max_tokens"`
+	Temperature float64         `yaml:"temperature"`
+	TopP        float64         `yaml:"top_p"`
+	Seed        *int            `yaml:"seed"`
+	Fallbacks   []BackendConfig `yaml:"fallbacks"`
+
+	// Transport settings for gateways. Without Proxy the HTTPS_PROXY and
+	// NO_PROXY environment variables apply; CACert is a PEM bundle added to
+	// the system roots; UnixSocket dials every request over the socket.
+	Proxy      string            `yaml:"proxy"`
+	CACert     string            `yaml:"ca_cert"`
+	ClientCert string            `yaml:"client_cert"`
+	ClientKey  string            `yaml:"client_key"`
+	UnixSocket string            `yaml:"unix_socket"`
+	Headers    map[string]string `yaml:"headers"`
+	Query      map[string]string `yaml:"query"`
+
+	// Provider is openai (the default) or azure. Azure endpoints are the
+	// resource URL; the deployment (default: the model) goes in the path,
+	// the key in the api-key header. OmitModel drops the model from request
+	// bodies and ModelMap renames it, for gateways that choose the model.
+	Provider   string            `yaml:"provider"`
+	Deployment string            `yaml:"deployment"`
+	APIVersion string            `yaml:"api_version"`
+	OmitModel  bool              `yaml:"omit_model"`
+	ModelMap   map[string]string `yaml:"model_map"`
+
+	// Profile adapts requests to the model: chat sends the OpenAI chat
+	// parameters, reasoning sends max_completion_tokens, temperature 1 and
+	// no top_p. Empty chooses by model name (o1, o3, o4, gpt-5 are reasoning).
+	Profile string `yaml:"profile"`
+
+	// Vision tells whether the model accepts images; unset, known text-only
+	// models are rejected and others are assumed to support them
+	Vision *bool `yaml:"vision"`
+}
+
+// BackendConfig represents a fallback endpoint, tried in order when the
+// previous one fails with a retryable error. An empty endpoint reuses the
+// primary endpoint and key, an empty model reuses the primary model.
+// Headers, Query, Provider and Deployment apply to the fallback's own
+// endpoint; those of the primary endpoint, and its Unix socket, are only
+// inherited when it is reused. Profile applies to the fallback's own model.
+type BackendConfig struct {
+	Name       string            `yaml:"name"`
+	Endpoint   string            `yaml:"endpoint"`
+	Key        string            `yaml:"key"`
+	Model      string            `yaml:"model"`
+	Headers    map[string]string `yaml:"headers"`
+	Query      map[string]string `yaml:"query"`
+	Provider   string            `yaml:"provider"`
+	Deployment string            `yaml:"deployment"`
+	Profile    string            `yaml:"profile"`
+}
+
+// RateLimitConfig represents the request and token limits of a provider and
+// model; empty Provider or Model match any
+type RateLimitConfig struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+	RPM      int    `yaml:"rpm"`
+	TPM      int    `yaml:"tpm"`
+}
+
+// HookConfig represents a processing step run before the translation request
+// (stage pre) or after the response (stage post). A hook either runs Command,
+// exchanging a HookPayload as JSON on stdin and stdout, or replaces matches of
+// Pattern with Replace. OnError is fail (the default) or skip.
+type HookConfig struct {
+	Name    string   `yaml:"name"`
+	Stage   string   `yaml:"stage"`
+	Command []string `yaml:"command"`
+	Timeout int      `yaml:"timeout"`
+	Pattern string   `yaml:"pattern"`
+	Replace string   `yaml:"replace"`
+	OnError string   `yaml:"on_error"`
+}
+
+// ScrubConfig represents the replacement of personal data and secrets with
+// placeholders before text is sent; empty Kinds means all kinds
+type ScrubConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Kinds   []string `yaml:"kinds"`
+}
+
+// HistoryConfig represents the log of translations kept in the log directory
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled"`
 }
 
 // LanguageConfig represents language-related configuration
 type LanguageConfig struct {
 	Common   []string `yaml:"common"`
 	Priority []string `yaml:"priority"`
+	Source   string   `yaml:"source"`
 }
 
 // CacheConfig represents cache-related configuration
 type CacheConfig struct {
-	Enabled   bool   `yaml:"enabled"`
-	Directory string `yaml:"directory"`
-	TTL       int    `yaml:"ttl"`
+	Enabled    bool `yaml:"enabled"`
+	TTL        int  `yaml:"ttl"`
+	MaxEntries int  `yaml:"max_entries"`
+}
+
+// ServerConfig represents HTTP server configuration
+type ServerConfig struct {
+	Addr            string `yaml:"addr"`
+	MaxBodyBytes    int64  `yaml:"max_body_bytes"`
+	MaxTextLength   int    `yaml:"max_text_length"`
+	MaxTargets      int    `yaml:"max_targets"`
+	MaxConcurrent   int    `yaml:"max_concurrent"`
+	ShutdownTimeout int    `yaml:"shutdown_timeout"`
+}
+
+// ContextWindowConfig represents document context settings used when
+// translating multi-paragraph text chunk by chunk
+type ContextWindowConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	TokenBudget int  `yaml:"token_budget"`
+	Summary     bool `yaml:"summary"`
+}
+
+// ChunkingConfig represents the splitting of long documents into chunks
+// translated concurrently; a zero TokenBudget means half of api.max_tokens
+type ChunkingConfig struct {
+	TokenBudget int `yaml:"token_budget"`
+	Concurrency int `yaml:"concurrency"`
+}
+
+// VerifyConfig represents back-translation quality check settings
+type VerifyConfig struct {
+	Enabled   bool    `yaml:"enabled"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// ClipboardConfig represents clipboard watch configuration
+type ClipboardConfig struct {
+	Interval  int  `yaml:"interval"`
+	Debounce  int  `yaml:"debounce"`
+	WriteBack bool `yaml:"write_back"`
 }
 
 // AdvancedConfig represents advanced configuration options
 type AdvancedConfig struct {
-	Debug          bool   `yaml:"debug"`
-	LogDir         string `yaml:"log_dir"`
-	PromptTemplate string `yaml:"prompt_template"`
+	Debug          bool                 `yaml:"debug"`
+	LogDir         string               `yaml:"log_dir"`
+	PromptTemplate string               `yaml:"prompt_template"`
+	SystemPrompt   string               `yaml:"system_prompt"`
+	Examples       map[string][]Example `yaml:"examples"`
+	Style          string               `yaml:"style"`
+	Styles         map[string]string    `yaml:"styles"`
+	Glossary       map[string]string    `yaml:"glossary"`
+	Context        string               `yaml:"context"`
+	Romanize       bool                 `yaml:"romanize"`
+
+	AlternativesTemplate string `yaml:"alternatives_template"`
+	ExplainTemplate      string `yaml:"explain_template"`
+	ImageTemplate        string `yaml:"image_template"`
 }
 
 // DefaultConfig returns a Config with default values
@@ -62,40 +212,86 @@ func DefaultConfig() *Config {
 			Common:   []string{"zh", "en", "ja", "ko", "es", "fr", "de", "ru", "pt", "it"},
 			Priority: []string{"zh", "en"},
 		},
+		ContextWindow: ContextWindowConfig{
+			Enabled:     false,
+			TokenBudget: 1000,
+			Summary:     true,
+		},
+		Chunking: ChunkingConfig{
+			TokenBudget: 0,
+			Concurrency: 4,
+		},
+		Verify: VerifyConfig{
+			Enabled:   false,
+			Threshold: 0.6,
+		},
+		Cache: CacheConfig{
+			Enabled:    true,
+			TTL:        720,
+			MaxEntries: 1000,
+		},
+		Server: ServerConfig{
+			Addr:            ":8080",
+			MaxBodyBytes:    1 << 20,
+			MaxTextLength:   10000,
+			MaxTargets:      10,
+			MaxConcurrent:   4,
+			ShutdownTimeout: 10,
+		},
+		Clipboard: ClipboardConfig{
+			Interval:  500,
+			Debounce:  300,
+			WriteBack: false,
+		},
+		History: HistoryConfig{
+			Enabled: true,
+		},
 		Advanced: AdvancedConfig{
-			Debug:  false,
-			LogDir: ".log/fanyi",
-			PromptTemplate: `You are a professional translator. Translate the following text to {language}.
-Only return the translated text without any explanation or additional content.
+			Debug:          false,
+			LogDir:         ".log/fanyi",
+			PromptTemplate: defaultPromptTemplate,
 
-Text: {input_text}`,
+			AlternativesTemplate: defaultAlternativesTemplate,
+			ExplainTemplate:      defaultExplainTemplate,
+			ImageTemplate:        defaultImageTemplate,
 		},
 	}
 }
 
 // Load loads configuration from file, environment variables, and defaults
 func Load() (*Config, error) {
+	cfg, err := Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read loads configuration like Load without validating it, for callers
+// that only need a few settings
+func Read() (*Config, error) {
 	cfg := DefaultConfig()
 
 	// Try to load from config file
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		configPath := filepath.Join(homeDir, ".config/fanyi/config.yaml")
+	if configDir, err := ConfigDir(); err == nil {
+		configPath := filepath.Join(configDir, "config.yaml")
 		if data, err := os.ReadFile(configPath); err == nil {
 			if err := yaml.Unmarshal(data, cfg); err != nil {
 				return nil, fmt.Errorf("failed to parse config file: %w", err)
 			}
+			cfg.markFileSources(data, configPath)
 		}
 	}
 
 	// Override with environment variables
 	cfg.applyEnvVars()
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, err
-	}
-
 	return cfg, nil
 }
 
@@ -104,12 +300,14 @@ func (c *Config) applyEnvVars() {
 	// API configuration
 	if endpoint := os.Getenv("FANYI_API_ENDPOINT"); endpoint != "" {
 		c.API.Endpoint = endpoint
+		c.SetSource("api.endpoint", "env FANYI_API_ENDPOINT")
 	}
 	if key := os.Getenv("FANYI_API_KEY"); key != "" {
 		c.API.Key = key
 	}
 	if model := os.Getenv("FANYI_API_MODEL"); model != "" {
 		c.API.Model = model
+		c.SetSource("api.model", "env FANYI_API_MODEL")
 	}
 	if timeout := os.Getenv("FANYI_API_TIMEOUT"); timeout != "" {
 		if val, err := strconv.Atoi(timeout); err == nil {
@@ -119,8 +317,39 @@ func (c *Config) applyEnvVars() {
 	if maxTokens := os.Getenv("FANYI_API_MAX_TOKENS"); maxTokens != "" {
 		if val, err := strconv.Atoi(maxTokens); err == nil {
 			c.API.MaxTokens = val
+			c.SetSource("api.max_tokens", "env FANYI_API_MAX_TOKENS")
+		}
+	}
+	if temperature := os.Getenv("FANYI_API_TEMPERATURE"); temperature != "" {
+		if val, err := strconv.ParseFloat(temperature, 64); err == nil {
+			c.API.Temperature = val
+			c.SetSource("api.temperature", "env FANYI_API_TEMPERATURE")
+		}
+	}
+	if topP := os.Getenv("FANYI_API_TOP_P"); topP != "" {
+		if val, err := strconv.ParseFloat(topP, 64); err == nil {
+			c.API.TopP = val
+			c.SetSource("api.top_p", "env FANYI_API_TOP_P")
+		}
+	}
+	if seed := os.Getenv("FANYI_API_SEED"); seed != "" {
+		if val, err := strconv.Atoi(seed); err == nil {
+			c.API.Seed = &val
+			c.SetSource("api.seed", "env FANYI_API_SEED")
 		}
 	}
+	if provider := os.Getenv("FANYI_API_PROVIDER"); provider != "" {
+		c.API.Provider = provider
+	}
+	if deployment := os.Getenv("FANYI_API_DEPLOYMENT"); deployment != "" {
+		c.API.Deployment = deployment
+	}
+	if version := os.Getenv("FANYI_API_VERSION"); version != "" {
+		c.API.APIVersion = version
+	}
+	if profile := os.Getenv("FANYI_API_PROFILE"); profile != "" {
+		c.API.Profile = profile
+	}
 
 	// Language configuration
 	if langs := os.Getenv("FANYI_LANGUAGES"); langs != "" {
@@ -130,6 +359,53 @@ func (c *Config) applyEnvVars() {
 		c.Languages.Priority = strings.Split(priority, ",")
 	}
 
+	// Context window configuration
+	if enabled := os.Getenv("FANYI_CONTEXT_WINDOW"); enabled != "" {
+		c.ContextWindow.Enabled = enabled == "true"
+	}
+	if budget := os.Getenv("FANYI_CONTEXT_TOKEN_BUDGET"); budget != "" {
+		if val, err := strconv.Atoi(budget); err == nil {
+			c.ContextWindow.TokenBudget = val
+		}
+	}
+
+	// Chunking configuration
+	if budget := os.Getenv("FANYI_CHUNK_TOKEN_BUDGET"); budget != "" {
+		if val, err := strconv.Atoi(budget); err == nil {
+			c.Chunking.TokenBudget = val
+		}
+	}
+	if concurrency := os.Getenv("FANYI_CHUNK_CONCURRENCY"); concurrency != "" {
+		if val, err := strconv.Atoi(concurrency); err == nil {
+			c.Chunking.Concurrency = val
+		}
+	}
+
+	// Verify configuration
+	if verify := os.Getenv("FANYI_VERIFY"); verify != "" {
+		c.Verify.Enabled = verify == "true"
+	}
+
+	// Scrub configuration
+	if scrub := os.Getenv("FANYI_SCRUB"); scrub != "" {
+		c.Scrub.Enabled = scrub == "true"
+	}
+
+	// History configuration
+	if enabled := os.Getenv("FANYI_HISTORY"); enabled != "" {
+		c.History.Enabled = enabled == "true"
+	}
+
+	// Cache configuration
+	if enabled := os.Getenv("FANYI_CACHE_ENABLED"); enabled != "" {
+		c.Cache.Enabled = enabled == "true"
+	}
+
+	// Server configuration
+	if addr := os.Getenv("FANYI_SERVER_ADDR"); addr != "" {
+		c.Server.Addr = addr
+	}
+
 	// Advanced configuration
 	if debug := os.Getenv("FANYI_DEBUG"); debug != "" {
 		c.Advanced.Debug = debug == "true"
@@ -137,6 +413,79 @@ func (c *Config) applyEnvVars() {
 	if logDir := os.Getenv("FANYI_LOG_DIR"); logDir != "" {
 		c.Advanced.LogDir = logDir
 	}
+	if style := os.Getenv("FANYI_STYLE"); style != "" {
+		c.Advanced.Style = style
+	}
+	if romanize := os.Getenv("FANYI_ROMANIZE"); romanize != "" {
+		c.Advanced.Romanize = romanize == "true"
+	}
+}
+
+// Setting is a resolved configuration value and where it was set: default,
+// the config file, an environment variable or a command line flag
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// tracedSettings are the settings reported by Resolved
+var tracedSettings = []struct {
+	key   string
+	value func(*Config) string
+}{
+	{"api.endpoint", func(c *Config) string { return c.API.Endpoint }},
+	{"api.model", func(c *Config) string { return c.API.Model }},
+	{"api.temperature", func(c *Config) string { return strconv.FormatFloat(c.API.Temperature, 'g', -1, 64) }},
+	{"api.max_tokens", func(c *Config) string { return strconv.Itoa(c.API.MaxTokens) }},
+	{"api.top_p", func(c *Config) string {
+		if c.API.TopP == 0 {
+			return "unset"
+		}
+		return strconv.FormatFloat(c.API.TopP, 'g', -1, 64)
+	}},
+	{"api.seed", func(c *Config) string {
+		if c.API.Seed == nil {
+			return "unset"
+		}
+		return strconv.Itoa(*c.API.Seed)
+	}},
+}
+
+// SetSource records where the setting key was last set
+func (c *Config) SetSource(key, source string) {
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	c.sources[key] = source
+}
+
+// markFileSources records the traced settings present in the config file
+func (c *Config) markFileSources(data []byte, path string) {
+	var present map[string]map[string]interface{}
+	if err := yaml.Unmarshal(data, &present); err != nil {
+		return
+	}
+	for _, s := range tracedSettings {
+		section, key, _ := strings.Cut(s.key, ".")
+		if _, ok := present[section][key]; ok {
+			c.SetSource(s.key, "file "+path)
+		}
+	}
+}
+
+// Resolved returns the model settings with the source that won, following
+// the precedence flag > env > file > default
+func (c *Config) Resolved() []Setting {
+	settings := make([]Setting, 0, len(tracedSettings))
+	for _, s := range tracedSettings {
+		source := c.sources[s.key]
+		if source == "" {
+			source = "default"
+		}
+		settings = append(settings, Setting{Key: s.key, Value: s.value(c), Source: source})
+	}
+	return settings
 }
 
 // Validate checks if the configuration is valid
@@ -147,15 +496,88 @@ func (c *Config) Validate() error {
 	if c.API.Key == "" {
 		return fmt.Errorf("API key is required (set FANYI_API_KEY or add to config file)")
 	}
-	if c.API.Model == "" {
-		return fmt.Errorf("API model is required")
+	if err := c.API.validateProvider(); err != nil {
+		return err
+	}
+	if err := c.API.validateProfile(); err != nil {
+		return fmt.Errorf("api: %w", err)
+	}
+	if c.API.Temperature < 0 || c.API.Temperature > 2 {
+		return fmt.Errorf("api.temperature must be between 0 and 2")
+	}
+	if c.API.TopP < 0 || c.API.TopP > 1 {
+		return fmt.Errorf("api.top_p must be between 0 and 1")
+	}
+	if c.API.MaxTokens < 0 {
+		return fmt.Errorf("api.max_tokens must not be negative")
 	}
 	if len(c.Languages.Priority) == 0 {
 		return fmt.Errorf("at least one priority language is required")
 	}
+	if c.ContextWindow.Enabled && c.ContextWindow.TokenBudget <= 0 {
+		return fmt.Errorf("context_window.token_budget must be positive")
+	}
+	if c.Chunking.TokenBudget < 0 {
+		return fmt.Errorf("chunking.token_budget must not be negative")
+	}
+	if c.Chunking.Concurrency <= 0 {
+		return fmt.Errorf("chunking.concurrency must be positive")
+	}
+	if (c.API.ClientCert == "") != (c.API.ClientKey == "") {
+		return fmt.Errorf("api.client_cert and api.client_key must be set together")
+	}
+	if _, err := newTransport(c.API); err != nil {
+		return fmt.Errorf("api: %w", err)
+	}
+	for i, b := range c.API.Fallbacks {
+		if b.Endpoint != "" && b.Key == "" {
+			return fmt.Errorf("api.fallbacks[%d]: key is required when endpoint is set", i)
+		}
+		if b.Endpoint == "" && b.Model == "" {
+			return fmt.Errorf("api.fallbacks[%d]: endpoint or model is required", i)
+		}
+		if err := c.withBackend(b).API.validateProvider(); err != nil {
+			return fmt.Errorf("api.fallbacks[%d]: %w", i, err)
+		}
+		if err := c.withBackend(b).API.validateProfile(); err != nil {
+			return fmt.Errorf("api.fallbacks[%d]: %w", i, err)
+		}
+	}
+	if c.Server.MaxConcurrent <= 0 {
+		return fmt.Errorf("server.max_concurrent must be positive")
+	}
+	if c.Clipboard.Interval <= 0 {
+		return fmt.Errorf("clipboard.interval must be positive")
+	}
+	for _, rl := range c.RateLimits {
+		if rl.RPM < 0 || rl.TPM < 0 {
+			return fmt.Errorf("rate_limits: rpm and tpm must not be negative")
+		}
+	}
+	if _, err := newHookPipeline(c.Hooks); err != nil {
+		return err
+	}
+	if err := validateScrubKinds(c.Scrub.Kinds); err != nil {
+		return fmt.Errorf("scrub.kinds: %w", err)
+	}
+	if c.Verify.Threshold < 0 || c.Verify.Threshold > 1 {
+		return fmt.Errorf("verify.threshold must be between 0 and 1")
+	}
+	if err := c.validatePrompt(); err != nil {
+		return err
+	}
 	return nil
 }
 
+// ConfigDir returns the directory of the config file, ~/.config/fanyi
+func ConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find home dir: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "fanyi"), nil
+}
+
 // GetLogDir returns the absolute path to the log directory
 func (c *Config) GetLogDir() string {
 	if filepath.IsAbs(c.Advanced.LogDir) {
@@ -167,3 +589,26 @@ func (c *Config) GetLogDir() string {
 	}
 	return filepath.Join(homeDir, c.Advanced.LogDir)
 }
+
+// withBackend returns a copy of the config using a fallback backend
+func (c *Config) withBackend(b BackendConfig) *Config {
+	cfg := *c
+	cfg.API.Name = b.Name
+	cfg.API.Fallbacks = nil
+	if b.Endpoint != "" {
+		cfg.API.Endpoint = b.Endpoint
+		cfg.API.Key = b.Key
+		cfg.API.Headers = b.Headers
+		cfg.API.Query = b.Query
+		cfg.API.Provider = b.Provider
+		cfg.API.Deployment = b.Deployment
+		cfg.API.UnixSocket = ""
+	}
+	if b.Model != "" || b.Profile != "" {
+		cfg.API.Profile = b.Profile
+	}
+	if b.Model != "" {
+		cfg.API.Model = b.Model
+	}
+	return &cfg
+}
@@ -0,0 +1,52 @@
+package src
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolvedPrecedence(t *testing.T) {
+	t.Setenv("FANYI_API_TEMPERATURE", "0.2")
+	t.Setenv("FANYI_API_MODEL", "env-model")
+
+	cfg := DefaultConfig()
+	file := []byte("api:\n  model: file-model\n  temperature: 0.5\n  max_tokens: 2000\n")
+	if err := yaml.Unmarshal(file, cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.markFileSources(file, "config.yaml")
+	cfg.applyEnvVars()
+	cfg.API.Model = "flag-model"
+	cfg.SetSource("api.model", "flag --model")
+
+	want := map[string]Setting{
+		"api.endpoint":    {Value: "https://api.openai.com/v1/chat/completions", Source: "default"},
+		"api.model":       {Value: "flag-model", Source: "flag --model"},
+		"api.temperature": {Value: "0.2", Source: "env FANYI_API_TEMPERATURE"},
+		"api.max_tokens":  {Value: "2000", Source: "file config.yaml"},
+		"api.top_p":       {Value: "unset", Source: "default"},
+	}
+	for _, s := range cfg.Resolved() {
+		w, ok := want[s.Key]
+		if !ok {
+			continue
+		}
+		if s.Value != w.Value || s.Source != w.Source {
+			t.Errorf("%s = %q from %q, want %q from %q", s.Key, s.Value, s.Source, w.Value, w.Source)
+		}
+	}
+}
+
+func TestValidateSampling(t *testing.T) {
+	cfg := testConfig("https://example.com")
+	cfg.API.Temperature = 2.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for temperature above 2")
+	}
+	cfg = testConfig("https://example.com")
+	cfg.API.TopP = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for top_p above 1")
+	}
+}
@@ -0,0 +1,125 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Conversation translates consecutive chunks of one document, carrying a
+// rolling summary and the previous chunk as context between requests
+type Conversation struct {
+	client          *Client
+	lang            string
+	scrubber        *Scrubber
+	summary         string
+	prevSource      string
+	prevTranslation string
+}
+
+// NewConversation starts a document translation into the given language
+func (c *Client) NewConversation(targetLanguage string) *Conversation {
+	return &Conversation{client: c, lang: targetLanguage, scrubber: c.scrubber()}
+}
+
+// Translate translates the next chunk of the document. All requests of the
+// conversation share one scrubber, so a value keeps its placeholder from
+// chunk to chunk.
+func (cv *Conversation) Translate(text string) (string, error) {
+	translation, err := cv.client.translate(text, cv.lang, cv.contextMessages(), cv.scrubber)
+	if err != nil {
+		return "", err
+	}
+
+	if cv.client.config.ContextWindow.Summary {
+		if err := cv.updateSummary(text, translation); err != nil && cv.client.config.Advanced.Debug {
+			fmt.Printf("[DEBUG] Summary update failed: %v\n", err)
+		}
+	}
+	cv.prevSource, cv.prevTranslation = text, translation
+	return translation, nil
+}
+
+// contextMessages returns the summary and previous chunk that fit in the token budget
+func (cv *Conversation) contextMessages() []Message {
+	budget := cv.client.config.ContextWindow.TokenBudget
+	var messages []Message
+
+	if cv.summary != "" {
+		summary := truncateTokens(cv.summary, budget/2)
+		budget -= EstimateTokens(summary)
+		messages = append(messages, Message{
+			Role: "system",
+			Content: TextContent("Summary of the earlier parts of the same document. " +
+				"Keep terminology, names and pronouns consistent with it:\n" + summary),
+		})
+	}
+
+	if cv.prevSource != "" {
+		prompt := cv.client.buildPrompt(cv.prevSource, cv.lang)
+		if EstimateTokens(prompt)+EstimateTokens(cv.prevTranslation) <= budget {
+			messages = append(messages,
+				Message{Role: "user", Content: TextContent(prompt)},
+				Message{Role: "assistant", Content: TextContent(cv.prevTranslation)},
+			)
+		}
+	}
+
+	return messages
+}
+
+// updateSummary asks the model to fold the latest chunk into the rolling summary
+func (cv *Conversation) updateSummary(source, translation string) error {
+	langName := getLanguageName(cv.lang)
+	prompt := fmt.Sprintf(`You maintain a running summary of a document that is being translated into %s.
+Update the summary with the new passage. Keep it under %d words.
+Record the topic, key terms and names together with their chosen %s renderings, and who each pronoun refers to.
+Return only the updated summary.
+
+Current summary:
+%s
+
+New passage:
+%s
+
+Translation:
+%s`, langName, cv.client.config.ContextWindow.TokenBudget/4, langName, cv.summary, source, translation)
+
+	resp, err := cv.client.complete(cv.client.newRequest([]Message{{Role: "user", Content: TextContent(prompt)}}), cv.scrubber)
+	if err != nil {
+		return err
+	}
+	cv.summary = strings.TrimSpace(resp.Choices[0].Message.Content.Text)
+	return nil
+}
+
+var paragraphSep = regexp.MustCompile(`\n[ \t]*\n`)
+
+// splitParagraphs splits text into non-empty paragraphs
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, p := range paragraphSep.Split(text, -1) {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// truncateTokens cuts text so that its estimated token count fits in limit
+func truncateTokens(text string, limit int) string {
+	if EstimateTokens(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if EstimateTokens(string(runes[:mid])) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
@@ -0,0 +1,166 @@
+package src
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed dictionary.schema.json
+var dictionarySchemaJSON []byte
+
+var dictionarySchema = func() *Schema {
+	s, err := ParseSchema(dictionarySchemaJSON)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}()
+
+// maxDictionaryCJKWord is the longest CJK input treated as a single word
+const maxDictionaryCJKWord = 4
+
+// DictionaryEntry is a dictionary-style result for a single word
+type DictionaryEntry struct {
+	Word           string         `json:"word"`
+	SourceLanguage string         `json:"source_language"`
+	TargetLanguage string         `json:"target_language"`
+	Pronunciation  string         `json:"pronunciation"`
+	Entries        []PartOfSpeech `json:"entries"`
+	Collocations   []Collocation  `json:"collocations,omitempty"`
+	Backend        string         `json:"backend,omitempty"`
+}
+
+// PartOfSpeech groups the senses of a word by part of speech
+type PartOfSpeech struct {
+	PartOfSpeech string  `json:"part_of_speech"`
+	Senses       []Sense `json:"senses"`
+}
+
+// Sense is one meaning of a word
+type Sense struct {
+	Definition  string         `json:"definition"`
+	Translation string         `json:"translation"`
+	Examples    []UsageExample `json:"examples,omitempty"`
+}
+
+// UsageExample is an example sentence with its translation
+type UsageExample struct {
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+}
+
+// Collocation is a common word combination with its translation
+type Collocation struct {
+	Phrase      string `json:"phrase"`
+	Translation string `json:"translation"`
+}
+
+// IsSingleWord reports whether text should be looked up in dictionary mode
+func IsSingleWord(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.IndexFunc(text, unicode.IsSpace) >= 0 {
+		return false
+	}
+	cjk := 0
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+		case unicode.IsLetter(r), r == '-', r == '\'':
+		default:
+			return false
+		}
+	}
+	return cjk <= maxDictionaryCJKWord
+}
+
+// Lookup asks the model for a dictionary entry of word
+func (c *Client) Lookup(word, sourceLanguage, targetLanguage string) (*DictionaryEntry, error) {
+	prompt := fmt.Sprintf(`You are a bilingual %[1]s-%[2]s dictionary. Write the dictionary entry for the %[1]s word below.
+Give its pronunciation (pinyin with tone marks for Chinese, kana reading for Japanese, Revised Romanization for Korean, IPA otherwise),
+its senses grouped by part of speech with definitions in %[2]s, a %[2]s translation and example sentences for each sense,
+and common collocations. Reply with JSON only, matching this JSON schema:
+%[3]s
+
+Word: %[4]s`, getLanguageName(sourceLanguage), getLanguageName(targetLanguage), dictionarySchemaJSON, word)
+
+	resp, err := c.Chat([]Message{{Role: "user", Content: TextContent(prompt)}})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := extractJSON(resp.Choices[0].Message.Content.Text)
+	if err != nil {
+		return nil, err
+	}
+	if err := dictionarySchema.ValidateJSON(data); err != nil {
+		return nil, fmt.Errorf("dictionary entry does not match schema: %w", err)
+	}
+
+	entry := &DictionaryEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("failed to parse dictionary entry: %w", err)
+	}
+	entry.SourceLanguage, entry.TargetLanguage = sourceLanguage, targetLanguage
+	return entry, nil
+}
+
+// Lookup returns a dictionary entry for word in targetLang, or in the first
+// priority language that differs from the word's language. Hooks cannot
+// rewrite a dictionary entry, so lookups fail when hooks are configured.
+func (t *Translator) Lookup(word, targetLang string) (*DictionaryEntry, error) {
+	if len(t.hooks.pre) > 0 || len(t.hooks.post) > 0 {
+		return nil, fmt.Errorf("dictionary lookups do not support hooks")
+	}
+	source := t.sourceLanguage(word)
+	if targetLang == "" {
+		targetLang = t.defaultTarget(word)
+	}
+
+	t.logger.Debug("calling API", "lang", targetLang, "dictionary", word)
+	var entry *DictionaryEntry
+	client, err := t.failover(func(c *Client) error {
+		var err error
+		entry, err = c.Lookup(word, source, targetLang)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dictionary lookup failed: %w", err)
+	}
+	entry.Backend = client.Name()
+	return entry, nil
+}
+
+// Format renders the dictionary entry for the terminal
+func (e *DictionaryEntry) Format(color bool) string {
+	var b strings.Builder
+	b.WriteString(c(e.Word, colorGreen+colorBold, color))
+	if e.Pronunciation != "" {
+		b.WriteString(" " + c("["+strings.Trim(e.Pronunciation, "[]/")+"]", colorGray, color))
+	}
+
+	for _, pos := range e.Entries {
+		b.WriteString("\n\n" + c(pos.PartOfSpeech, colorMagenta+colorBold, color))
+		for i, sense := range pos.Senses {
+			fmt.Fprintf(&b, "\n  %s %s", c(fmt.Sprintf("%d.", i+1), colorMagenta, color), c(sense.Translation, colorBold, color))
+			if sense.Definition != "" && sense.Definition != sense.Translation {
+				b.WriteString(" " + c(sense.Definition, colorGray, color))
+			}
+			for _, ex := range sense.Examples {
+				b.WriteString("\n     " + c("• ", colorGray, color) + ex.Source)
+				b.WriteString("\n       " + c(ex.Translation, colorDim, color))
+			}
+		}
+	}
+
+	if len(e.Collocations) > 0 {
+		b.WriteString("\n\n" + c("Collocations", colorGray+colorBold, color))
+		for _, col := range e.Collocations {
+			fmt.Fprintf(&b, "\n  %s %s %s", col.Phrase, c("→", colorMagenta, color), col.Translation)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsSingleWord(t *testing.T) {
+	tests := map[string]bool{
+		"serendipity":  true,
+		"  don't ":     true,
+		"苹果":           true,
+		"hello world":  false,
+		"我有一个苹果":       false,
+		"v1.2":         false,
+		"":             false,
+		"state-of-art": true,
+	}
+	for text, want := range tests {
+		if got := IsSingleWord(text); got != want {
+			t.Errorf("IsSingleWord(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestDictionarySchema(t *testing.T) {
+	valid := `{"word": "apple", "pronunciation": "ˈæpəl",
+		"entries": [{"part_of_speech": "noun", "senses": [{"definition": "a fruit", "translation": "苹果",
+			"examples": [{"source": "I ate an apple.", "translation": "我吃了一个苹果。"}]}]}],
+		"collocations": [{"phrase": "apple pie", "translation": "苹果派"}]}`
+	if err := dictionarySchema.ValidateJSON([]byte(valid)); err != nil {
+		t.Errorf("valid entry rejected: %v", err)
+	}
+
+	tests := map[string]string{
+		`{"word": "apple", "pronunciation": "x"}`:                                                                                       `missing required field "entries"`,
+		`{"word": "apple", "pronunciation": "x", "entries": []}`:                                                                        "at least 1 items",
+		`{"word": "apple", "pronunciation": "x", "entries": [{"part_of_speech": 1}]}`:                                                   "missing required field",
+		`{"word": "", "pronunciation": "x", "entries": [{"part_of_speech": "n", "senses": [{"definition": "d", "translation": "t"}]}]}`: "$.word",
+	}
+	for input, want := range tests {
+		err := dictionarySchema.ValidateJSON([]byte(input))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateJSON(%s) error = %v, want %q", input, err, want)
+		}
+	}
+}
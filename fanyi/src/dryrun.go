@@ -0,0 +1,71 @@
+package src
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DryRun lists the requests a translation would send, without sending them
+type DryRun struct {
+	Requests []*PreparedRequest `json:"requests"`
+}
+
+// PreparedRequest is a request as it would be sent to the API
+type PreparedRequest struct {
+	Language   string      `json:"language"`
+	Backend    string      `json:"backend"`
+	Endpoint   string      `json:"endpoint"`
+	Redactions []Redaction `json:"redactions,omitempty"`
+	Body       ChatRequest `json:"body"`
+}
+
+// DryRun returns the requests that translating text to targetLang, or to the
+// priority languages, would send to the primary backend after the pre hooks
+// and scrubbing have run
+func (t *Translator) DryRun(text, targetLang string) (*DryRun, error) {
+	langs := t.config.Languages.Priority
+	if targetLang != "" {
+		langs = []string{targetLang}
+	}
+
+	result := &DryRun{}
+	for _, lang := range langs {
+		p, err := t.preHooks(text, lang)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint, err := t.client.endpointURL()
+		if err != nil {
+			return nil, err
+		}
+		s := t.client.scrubber()
+		body := t.client.profile().adapt(t.client.translateRequest(p.Text, lang, nil))
+		body.Messages = s.scrubMessages(body.Messages)
+		result.Requests = append(result.Requests, &PreparedRequest{
+			Language:   lang,
+			Backend:    t.client.Name(),
+			Endpoint:   endpoint,
+			Redactions: s.Redactions(),
+			Body:       body,
+		})
+	}
+	return result, nil
+}
+
+// Format renders the requests for the terminal
+func (d *DryRun) Format(color bool) string {
+	var parts []string
+	for _, r := range d.Requests {
+		var b strings.Builder
+		b.WriteString(c(getLanguageName(r.Language)+":", colorGreen+colorBold, color))
+		b.WriteString(" " + c("POST "+r.Endpoint+" ("+r.Backend+")", colorGray, color))
+		for _, red := range r.Redactions {
+			b.WriteString("\n  " + c(red.Placeholder, colorYellow, color) + " " + c("← "+red.Kind+" "+red.Value, colorDim, color))
+		}
+		data, _ := json.MarshalIndent(r.Body, "", "  ")
+		b.WriteString("\n" + string(data))
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "\n\n")
+}
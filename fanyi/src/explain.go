@@ -0,0 +1,139 @@
+package src
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GlossEntry is one word or phrase of a word-by-word gloss
+type GlossEntry struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Explanation holds a translation with its gloss and grammar notes
+type Explanation struct {
+	Language    string       `json:"language"`
+	Translation string       `json:"translation"`
+	Gloss       []GlossEntry `json:"gloss"`
+	Grammar     string       `json:"grammar"`
+	Idioms      string       `json:"idioms"`
+	Backend     string       `json:"backend,omitempty"`
+}
+
+// Explain translates text and asks for a word-by-word gloss and notes
+func (c *Client) Explain(text, targetLanguage string) (*Explanation, error) {
+	messages := c.buildTemplateMessages(c.config.Advanced.ExplainTemplate, defaultExplainTemplate, text, targetLanguage)
+
+	resp, err := c.Chat(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{Language: targetLanguage}
+	if err := parseJSONAnswer(resp.Choices[0].Message.Content.Text, explanation); err != nil {
+		return nil, err
+	}
+	if explanation.Translation == "" {
+		return nil, fmt.Errorf("explanation has no translation")
+	}
+	return explanation, nil
+}
+
+// ExplainResult holds the explained translations of one input text
+type ExplainResult struct {
+	Original     string         `json:"original"`
+	Explanations []*Explanation `json:"explanations"`
+}
+
+// Explain translates text to the target language(s) with a gloss and notes
+func (t *Translator) Explain(text, targetLang string) (*ExplainResult, error) {
+	result := &ExplainResult{Original: text}
+	for _, lang := range t.targetLanguages(targetLang) {
+		t.logger.Debug("calling API", "lang", lang, "explain", true)
+		explanation, client, err := t.explain(text, lang)
+		if err != nil {
+			if targetLang != "" {
+				return nil, fmt.Errorf("translation failed: %w", err)
+			}
+			t.logger.Error("failed to translate", "lang", lang, "err", err)
+			continue
+		}
+		explanation.Backend = client.Name()
+		result.Explanations = append(result.Explanations, explanation)
+	}
+
+	if len(result.Explanations) == 0 {
+		return nil, fmt.Errorf("failed to translate to any language")
+	}
+	return result, nil
+}
+
+// explain asks the backends to explain the translation of text into lang,
+// passing the text through the pre hooks and the translation through the
+// post hooks. The gloss and notes are kept as the model wrote them.
+func (t *Translator) explain(text, lang string) (*Explanation, *Client, error) {
+	var explanation *Explanation
+	client, err := t.hooked([]string{text}, lang, func(c *Client, texts []string) ([][]*string, error) {
+		var err error
+		if explanation, err = c.Explain(texts[0], lang); err != nil {
+			return nil, err
+		}
+		return [][]*string{{&explanation.Translation}}, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return explanation, client, nil
+}
+
+// Format renders the explanations for the terminal
+func (r *ExplainResult) Format(color bool) string {
+	blocks := make([]string, 0, len(r.Explanations))
+	for _, e := range r.Explanations {
+		blocks = append(blocks, formatExplanation(e, color))
+	}
+	return formatMultiOutput(r.Original, blocks, color)
+}
+
+func formatExplanation(e *Explanation, color bool) string {
+	var b strings.Builder
+	b.WriteString(formatLine(getLanguageName(e.Language), e.Translation, color))
+
+	if len(e.Gloss) > 0 {
+		width := 0
+		for _, g := range e.Gloss {
+			width = max(width, displayWidth(g.Source))
+		}
+		b.WriteString("\n  " + c("Gloss", colorGray+colorBold, color))
+		for _, g := range e.Gloss {
+			pad := strings.Repeat(" ", width-displayWidth(g.Source))
+			fmt.Fprintf(&b, "\n    %s%s %s %s", g.Source, pad, c("→", colorMagenta, color), g.Target)
+			if g.Note != "" {
+				b.WriteString("  " + c(g.Note, colorDim, color))
+			}
+		}
+	}
+	if e.Grammar != "" {
+		b.WriteString("\n  " + c("Grammar", colorGray+colorBold, color) + ": " + e.Grammar)
+	}
+	if e.Idioms != "" {
+		b.WriteString("\n  " + c("Idioms", colorGray+colorBold, color) + ": " + e.Idioms)
+	}
+	return b.String()
+}
+
+// displayWidth returns the terminal column width of s, counting CJK
+// characters as two columns
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isCJK(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
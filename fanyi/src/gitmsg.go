@@ -0,0 +1,78 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// scissorsLine is the line below which git puts the diff with
+// commit --verbose; it and everything after it are kept
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// trailerRe matches a git trailer such as Signed-off-by: or Fixes:
+var trailerRe = regexp.MustCompile(`^[A-Za-z0-9-]+: \S`)
+
+// TranslateCommitMessage translates a commit message as git passes it to the
+// commit-msg hook. Comment lines, the diff below the scissors line and the
+// trailers are kept unchanged. A message already written in the target
+// language is returned as is. Without targetLang the message is translated
+// to the first priority language that differs from its own; the language
+// used is returned.
+func (t *Translator) TranslateCommitMessage(msg, targetLang string) (string, string, error) {
+	var tail string
+	if i := strings.Index(msg, scissorsLine); i >= 0 && (i == 0 || msg[i-1] == '\n') {
+		msg, tail = msg[:i], msg[i:]
+	}
+
+	var body, comments []string
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, "#") {
+			comments = append(comments, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(body, "\n"))
+	if text == "" {
+		return msg + tail, targetLang, nil
+	}
+
+	// Trailers are the last paragraph if all its lines are trailers
+	var trailers string
+	if i := strings.LastIndex(text, "\n\n"); i >= 0 {
+		last := text[i+2:]
+		isTrailers := true
+		for _, line := range strings.Split(last, "\n") {
+			isTrailers = isTrailers && trailerRe.MatchString(line)
+		}
+		if isTrailers {
+			text, trailers = text[:i], last
+		}
+	}
+
+	lang := targetLang
+	if lang == "" {
+		lang = t.defaultTarget(text)
+	}
+	if writtenIn(text, lang) {
+		return msg + tail, lang, nil
+	}
+	translation, err := t.translateToLanguage(text, lang)
+	if err != nil {
+		return "", "", fmt.Errorf("translation failed: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(translation.Text) + "\n")
+	if trailers != "" {
+		b.WriteString("\n" + trailers + "\n")
+	}
+	if len(comments) > 0 {
+		b.WriteString("\n" + strings.Join(comments, "\n") + "\n")
+	}
+	if tail != "" {
+		b.WriteString(tail)
+	}
+	return b.String(), lang, nil
+}
@@ -0,0 +1,300 @@
+package src
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyFile is the name of the history log in the log directory
+const historyFile = "history.jsonl"
+
+// History kinds of the recorded results
+const (
+	HistoryTranslation  = "translation"
+	HistoryLookup       = "lookup"
+	HistoryAlternatives = "alternatives"
+	HistoryExplanation  = "explanation"
+	HistoryImage        = "image"
+	HistoryDocument     = "document"
+)
+
+// HistoryEntry is one recorded translation. ID is its 1-based position in the
+// history; it is not stored.
+type HistoryEntry struct {
+	ID       int             `json:"id,omitempty"`
+	Time     time.Time       `json:"time"`
+	Kind     string          `json:"kind"`
+	Input    string          `json:"input"`
+	Targets  []string        `json:"targets"`
+	Outputs  []HistoryOutput `json:"outputs"`
+	Model    string          `json:"model"`
+	Rendered string          `json:"rendered"`
+}
+
+// HistoryOutput is the output of an entry in one language
+type HistoryOutput struct {
+	Language string `json:"language"`
+	Text     string `json:"text"`
+	Backend  string `json:"backend,omitempty"`
+}
+
+// History is the append-only log of translations, one JSON object per line
+type History struct {
+	path string
+}
+
+// NewHistory returns the history stored in the log directory
+func NewHistory(cfg *Config) *History {
+	return &History{path: filepath.Join(cfg.GetLogDir(), historyFile)}
+}
+
+// Path returns the location of the history file
+func (h *History) Path() string {
+	return h.path
+}
+
+// Append adds an entry to the end of the history
+func (h *History) Append(entry *HistoryEntry) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create log dir: %w", err)
+	}
+	stored := *entry
+	stored.ID = 0
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	// A single write keeps concurrent appends from interleaving
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return f.Close()
+}
+
+// Entries returns all entries, oldest first. Lines that cannot be parsed,
+// such as a partial last line after a crash, are skipped but keep their ID.
+func (h *History) Entries() ([]*HistoryEntry, error) {
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []*HistoryEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 64<<20)
+	for id := 1; sc.Scan(); id++ {
+		var entry HistoryEntry
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			continue
+		}
+		entry.ID = id
+		entries = append(entries, &entry)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// Recent returns the last n entries, oldest first
+func (h *History) Recent(n int) (HistoryList, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, nil
+}
+
+// Search returns the entries whose input or outputs contain all terms,
+// ignoring case
+func (h *History) Search(terms ...string) (HistoryList, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var matches HistoryList
+	for _, entry := range entries {
+		if entry.matches(terms) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+// Get returns the entry with the given ID
+func (h *History) Get(id int) (*HistoryEntry, error) {
+	entries, err := h.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("no history entry %d", id)
+}
+
+func (e *HistoryEntry) matches(terms []string) bool {
+	texts := []string{strings.ToLower(e.Input)}
+	for _, out := range e.Outputs {
+		texts = append(texts, strings.ToLower(out.Text))
+	}
+	all := strings.Join(texts, "\n")
+	for _, term := range terms {
+		if !strings.Contains(all, strings.ToLower(term)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Format reprints the entry as it was shown when it was recorded
+func (e *HistoryEntry) Format(color bool) string {
+	header := fmt.Sprintf("#%d %s %s", e.ID, e.Time.Local().Format("2006-01-02 15:04"), e.Model)
+	return c(header, colorGray, color) + "\n" + e.Rendered
+}
+
+// HistoryList is a list of history entries
+type HistoryList []*HistoryEntry
+
+// Format renders one line per entry
+func (l HistoryList) Format(color bool) string {
+	if len(l) == 0 {
+		return c("No history entries", colorDim, color)
+	}
+	lines := make([]string, 0, len(l))
+	for _, e := range l {
+		var outputs []string
+		for _, out := range e.Outputs {
+			outputs = append(outputs, out.Language+": "+preview(out.Text, 40))
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s %s %s",
+			c(fmt.Sprintf("%4d", e.ID), colorGreen+colorBold, color),
+			c(e.Time.Local().Format("2006-01-02 15:04"), colorGray, color),
+			preview(e.Input, 40), c("→", colorMagenta, color), strings.Join(outputs, " | ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteCSV writes the entries as CSV, one row per output
+func (l HistoryList) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "time", "kind", "model", "input", "language", "output", "backend"})
+	for _, e := range l {
+		for _, out := range e.Outputs {
+			cw.Write([]string{strconv.Itoa(e.ID), e.Time.Format(time.RFC3339), e.Kind, e.Model,
+				e.Input, out.Language, out.Text, out.Backend})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the entries as a JSON array
+func (l HistoryList) WriteJSON(w io.Writer) error {
+	if l == nil {
+		l = HistoryList{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+// Record appends entry to the history if it is enabled
+func (t *Translator) Record(entry *HistoryEntry) error {
+	if !t.config.History.Enabled {
+		return nil
+	}
+	return NewHistory(t.config).Append(entry)
+}
+
+// NewHistoryEntry describes a result for the history
+func (t *Translator) NewHistoryEntry(input string, out Output) *HistoryEntry {
+	entry := &HistoryEntry{
+		Time:     time.Now(),
+		Input:    input,
+		Model:    t.config.API.Model,
+		Rendered: out.Format(false),
+	}
+	add := func(lang, text, backend string) {
+		entry.Targets = append(entry.Targets, lang)
+		entry.Outputs = append(entry.Outputs, HistoryOutput{Language: lang, Text: text, Backend: backend})
+	}
+
+	switch r := out.(type) {
+	case *Result:
+		entry.Kind = HistoryTranslation
+		for _, tr := range r.Translations {
+			add(tr.Language, tr.Text, tr.Backend)
+		}
+	case *DictionaryEntry:
+		entry.Kind = HistoryLookup
+		var senses []string
+		for _, pos := range r.Entries {
+			for _, s := range pos.Senses {
+				senses = append(senses, s.Translation)
+			}
+		}
+		add(r.TargetLanguage, strings.Join(senses, "; "), r.Backend)
+	case *AlternativesResult:
+		entry.Kind = HistoryAlternatives
+		for _, a := range r.Languages {
+			var variants []string
+			for _, v := range a.Variants {
+				variants = append(variants, v.Text)
+			}
+			add(a.Language, strings.Join(variants, "\n"), a.Backend)
+		}
+	case *ExplainResult:
+		entry.Kind = HistoryExplanation
+		for _, e := range r.Explanations {
+			add(e.Language, e.Translation, e.Backend)
+		}
+	case *ImageResult:
+		entry.Kind = HistoryImage
+		entry.Input = r.Image
+		for _, tr := range r.Translations {
+			var lines []string
+			for _, line := range tr.Lines {
+				lines = append(lines, line.Translation)
+			}
+			add(tr.Language, strings.Join(lines, "\n"), tr.Backend)
+		}
+	}
+	return entry
+}
+
+// NewDocumentHistoryEntry describes a translated document for the history
+func (t *Translator) NewDocumentHistoryEntry(input, lang, output string) *HistoryEntry {
+	return &HistoryEntry{
+		Time:     time.Now(),
+		Kind:     HistoryDocument,
+		Input:    input,
+		Targets:  []string{lang},
+		Outputs:  []HistoryOutput{{Language: lang, Text: output}},
+		Model:    t.config.API.Model,
+		Rendered: output,
+	}
+}
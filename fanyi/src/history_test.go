@@ -0,0 +1,157 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testHistory(t *testing.T) *History {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Advanced.LogDir = t.TempDir()
+	return NewHistory(cfg)
+}
+
+func TestHistoryAppendAndSearch(t *testing.T) {
+	history := testHistory(t)
+	entries := []*HistoryEntry{
+		{Kind: HistoryTranslation, Input: "Good morning", Outputs: []HistoryOutput{{Language: "zh", Text: "早上好"}}},
+		{Kind: HistoryTranslation, Input: "Good night", Outputs: []HistoryOutput{{Language: "zh", Text: "晚安"}}},
+		{Kind: HistoryLookup, Input: "serendipity", Outputs: []HistoryOutput{{Language: "zh", Text: "意外发现的好运"}}},
+	}
+	for _, e := range entries {
+		if err := history.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recent, err := history.Recent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].ID != 2 || recent[1].Input != "serendipity" {
+		t.Errorf("got recent %+v", recent)
+	}
+
+	for terms, want := range map[string][]int{
+		"good":         {1, 2},
+		"GOOD MORNING": {1},
+		"晚安":           {2},
+		"good 好运":      nil,
+	} {
+		matches, err := history.Search(strings.Fields(terms)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, m := range matches {
+			ids = append(ids, m.ID)
+		}
+		if len(ids) != len(want) || (len(ids) > 0 && ids[0] != want[0]) {
+			t.Errorf("search %q got %v, want %v", terms, ids, want)
+		}
+	}
+
+	if _, err := history.Get(4); err == nil {
+		t.Error("expected error for missing entry")
+	}
+}
+
+func TestHistorySkipsBrokenLines(t *testing.T) {
+	history := testHistory(t)
+	history.Append(&HistoryEntry{Input: "first"})
+	f, _ := os.OpenFile(history.Path(), os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString("{\"input\": \"trunc\n")
+	f.Close()
+	history.Append(&HistoryEntry{Input: "third"})
+
+	entry, err := history.Get(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Input != "third" {
+		t.Errorf("got entry 3 %q", entry.Input)
+	}
+	if info, _ := os.Stat(history.Path()); info.Mode().Perm() != 0o600 {
+		t.Errorf("history has mode %v", info.Mode().Perm())
+	}
+}
+
+func TestHistoryEntryFromResult(t *testing.T) {
+	srv, _ := newTestServer(t, func(req ChatRequest) string {
+		if strings.Contains(req.Messages[len(req.Messages)-1].Content.Text, "Chinese") {
+			return "你好"
+		}
+		return "Bonjour"
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Advanced.LogDir = t.TempDir()
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.TranslateTo("Hello", []string{"zh", "fr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trans.Record(trans.NewHistoryEntry("Hello", result)); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := NewHistory(cfg).Get(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Kind != HistoryTranslation || entry.Model != cfg.API.Model ||
+		strings.Join(entry.Targets, ",") != "zh,fr" || entry.Outputs[1].Text != "Bonjour" {
+		t.Errorf("got entry %+v", entry)
+	}
+	if !strings.HasSuffix(entry.Format(false), "\n"+result.Format(false)) {
+		t.Errorf("show does not reprint the result:\n%s", entry.Format(false))
+	}
+
+	cfg.History.Enabled = false
+	trans.Record(trans.NewHistoryEntry("Hello", result))
+	if entries, _ := NewHistory(cfg).Entries(); len(entries) != 1 {
+		t.Errorf("got %d entries with history disabled", len(entries))
+	}
+}
+
+func TestHistoryExport(t *testing.T) {
+	when := time.Date(2026, 10, 17, 9, 12, 0, 0, time.UTC)
+	list := HistoryList{{
+		ID: 1, Time: when, Kind: HistoryTranslation, Input: "Hi, there", Model: "gpt-4o",
+		Outputs: []HistoryOutput{{Language: "zh", Text: "你好"}, {Language: "fr", Text: "Salut", Backend: "backup"}},
+	}}
+
+	var csvOut bytes.Buffer
+	if err := list.WriteCSV(&csvOut); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,time,kind,model,input,language,output,backend\n" +
+		"1,2026-10-17T09:12:00Z,translation,gpt-4o,\"Hi, there\",zh,你好,\n" +
+		"1,2026-10-17T09:12:00Z,translation,gpt-4o,\"Hi, there\",fr,Salut,backup\n"
+	if csvOut.String() != want {
+		t.Errorf("got CSV\n%s", csvOut.String())
+	}
+
+	var jsonOut bytes.Buffer
+	if err := list.WriteJSON(&jsonOut); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []HistoryEntry
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0].ID != 1 || decoded[0].Outputs[1].Backend != "backup" {
+		t.Errorf("got JSON %s", jsonOut.String())
+	}
+
+	jsonOut.Reset()
+	HistoryList(nil).WriteJSON(&jsonOut)
+	if strings.TrimSpace(jsonOut.String()) != "[]" {
+		t.Errorf("empty history exported as %s", jsonOut.String())
+	}
+}
@@ -0,0 +1,237 @@
+package src
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Hook stages
+const (
+	hookStagePre  = "pre"
+	hookStagePost = "post"
+)
+
+// Hook error policies
+const (
+	hookOnErrorFail = "fail"
+	hookOnErrorSkip = "skip"
+)
+
+// defaultHookTimeout limits how long a command hook may run, in seconds
+const defaultHookTimeout = 10
+
+// HookPayload is the document exchanged with hooks. Command hooks receive it
+// as JSON on stdin and answer with the text and metadata as JSON on stdout.
+// Metadata set by pre hooks is passed on to the post hooks of the same
+// translation, so a pre hook can mask text that a post hook restores.
+type HookPayload struct {
+	Stage          string            `json:"stage"`
+	Text           string            `json:"text"`
+	SourceLanguage string            `json:"source_language"`
+	TargetLanguage string            `json:"target_language"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// hook transforms a payload in place
+type hook interface {
+	run(p *HookPayload) error
+}
+
+// configuredHook is a hook with its configuration
+type configuredHook struct {
+	config HookConfig
+	hook   hook
+}
+
+// hookPipeline holds the hooks run before and after a translation request
+type hookPipeline struct {
+	pre, post []configuredHook
+}
+
+// newHookPipeline builds the hooks in configuration order
+func newHookPipeline(configs []HookConfig) (*hookPipeline, error) {
+	p := &hookPipeline{}
+	for i, hc := range configs {
+		h, err := newHook(hc)
+		if err != nil {
+			return nil, fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+		if hc.Stage == hookStagePost {
+			p.post = append(p.post, configuredHook{hc, h})
+		} else {
+			p.pre = append(p.pre, configuredHook{hc, h})
+		}
+	}
+	return p, nil
+}
+
+// newHook creates the hook described by hc
+func newHook(hc HookConfig) (hook, error) {
+	if hc.Stage != hookStagePre && hc.Stage != hookStagePost {
+		return nil, fmt.Errorf("stage must be %q or %q", hookStagePre, hookStagePost)
+	}
+	if hc.OnError != "" && hc.OnError != hookOnErrorFail && hc.OnError != hookOnErrorSkip {
+		return nil, fmt.Errorf("on_error must be %q or %q", hookOnErrorFail, hookOnErrorSkip)
+	}
+	switch {
+	case len(hc.Command) > 0 && hc.Pattern != "":
+		return nil, fmt.Errorf("command and pattern are mutually exclusive")
+	case len(hc.Command) > 0:
+		timeout := hc.Timeout
+		if timeout <= 0 {
+			timeout = defaultHookTimeout
+		}
+		return &commandHook{args: hc.Command, timeout: time.Duration(timeout) * time.Second}, nil
+	case hc.Pattern != "":
+		re, err := regexp.Compile(hc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return &regexHook{re: re, replace: hc.Replace}, nil
+	default:
+		return nil, fmt.Errorf("command or pattern is required")
+	}
+}
+
+// runHooks passes p through hooks in order. A failing hook aborts the pipeline
+// unless its on_error policy is skip, in which case its changes are dropped.
+func (t *Translator) runHooks(hooks []configuredHook, p *HookPayload) error {
+	for i, h := range hooks {
+		name := h.config.Name
+		if name == "" {
+			name = fmt.Sprintf("%s hook %d", p.Stage, i+1)
+		}
+
+		attempt := *p
+		attempt.Metadata = make(map[string]string, len(p.Metadata))
+		for k, v := range p.Metadata {
+			attempt.Metadata[k] = v
+		}
+		if err := h.hook.run(&attempt); err != nil {
+			if h.config.OnError == hookOnErrorSkip {
+				t.logger.Warn("hook failed, skipping", "hook", name, "err", err)
+				continue
+			}
+			return fmt.Errorf("hook %s: %w", name, err)
+		}
+		*p = attempt
+	}
+	return nil
+}
+
+// preHooks passes text through the pre hooks and returns the payload whose
+// text is sent and whose metadata reaches postHooks
+func (t *Translator) preHooks(text, lang string) (*HookPayload, error) {
+	p := &HookPayload{Stage: hookStagePre, Text: text, SourceLanguage: t.sourceLanguage(text), TargetLanguage: lang}
+	if err := t.runHooks(t.hooks.pre, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// postHooks passes a translation of the text in p through the post hooks.
+// p is left unchanged, so it can be used for several translations.
+func (t *Translator) postHooks(p *HookPayload, translation string) (string, error) {
+	post := *p
+	post.Stage, post.Text = hookStagePost, translation
+	if err := t.runHooks(t.hooks.post, &post); err != nil {
+		return "", err
+	}
+	return post.Text, nil
+}
+
+// hooked passes texts through the pre hooks and calls fn with the hooked
+// texts on each backend in turn until one succeeds, returning that backend.
+// For each text fn returns the translations to pass through the post hooks
+// with the metadata of that text.
+func (t *Translator) hooked(texts []string, lang string, fn func(c *Client, texts []string) ([][]*string, error)) (*Client, error) {
+	payloads := make([]*HookPayload, len(texts))
+	hooked := make([]string, len(texts))
+	for i, text := range texts {
+		p, err := t.preHooks(text, lang)
+		if err != nil {
+			return nil, err
+		}
+		payloads[i], hooked[i] = p, p.Text
+	}
+
+	var outputs [][]*string
+	client, err := t.failover(func(c *Client) error {
+		var err error
+		outputs, err = fn(c, hooked)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, outs := range outputs {
+		for _, out := range outs {
+			if *out, err = t.postHooks(payloads[i], *out); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
+
+// regexHook replaces the matches of a regular expression
+type regexHook struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+func (h *regexHook) run(p *HookPayload) error {
+	p.Text = h.re.ReplaceAllString(p.Text, h.replace)
+	return nil
+}
+
+// commandHook runs an external command speaking JSON on stdin and stdout
+type commandHook struct {
+	args    []string
+	timeout time.Duration
+}
+
+func (h *commandHook) run(p *HookPayload) error {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, h.args[0], h.args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+
+	var output struct {
+		Text     *string           `json:"text"`
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return fmt.Errorf("invalid JSON output: %w", err)
+	}
+	if output.Text == nil {
+		return fmt.Errorf("output has no text field")
+	}
+	p.Text = *output.Text
+	for k, v := range output.Metadata {
+		if p.Metadata == nil {
+			p.Metadata = map[string]string{}
+		}
+		p.Metadata[k] = v
+	}
+	return nil
+}
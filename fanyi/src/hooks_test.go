@@ -0,0 +1,183 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestHookHelperProcess is run as a command hook by the tests below
+func TestHookHelperProcess(t *testing.T) {
+	if os.Getenv("FANYI_HOOK_HELPER") != "1" {
+		return
+	}
+	var p HookPayload
+	json.NewDecoder(os.Stdin).Decode(&p)
+	switch os.Args[len(os.Args)-1] {
+	case "mask":
+		p.Metadata = map[string]string{"[EMAIL]": "alice@example.com"}
+		p.Text = strings.ReplaceAll(p.Text, "alice@example.com", "[EMAIL]")
+	case "unmask":
+		for placeholder, value := range p.Metadata {
+			p.Text = strings.ReplaceAll(p.Text, placeholder, value)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "boom")
+		os.Exit(1)
+	}
+	json.NewEncoder(os.Stdout).Encode(p)
+	os.Exit(0)
+}
+
+func helperCommand(t *testing.T, mode string) []string {
+	t.Setenv("FANYI_HOOK_HELPER", "1")
+	return []string{os.Args[0], "-test.run=^TestHookHelperProcess$", "--", mode}
+}
+
+func TestHooksAroundTranslation(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		return "写信给 [EMAIL]，colour"
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Hooks = []HookConfig{
+		{Name: "mask", Stage: "pre", Command: helperCommand(t, "mask")},
+		{Name: "broken", Stage: "pre", Command: helperCommand(t, "fail"), OnError: "skip"},
+		{Name: "unmask", Stage: "post", Command: helperCommand(t, "unmask")},
+		{Name: "house style", Stage: "post", Pattern: `\bcolour\b`, Replace: "color"},
+	}
+	trans, err := NewTranslator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := trans.Translate("Write to alice@example.com", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := result.Translations[0].Text, "写信给 alice@example.com，color"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if prompt := (*requests)[0].Messages[0].Content.Text; strings.Contains(prompt, "alice@example.com") {
+		t.Errorf("email was sent to the API: %q", prompt)
+	}
+}
+
+func TestHookFailureAbortsTranslation(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "你好" })
+	cfg := testConfig(srv.URL)
+	cfg.Hooks = []HookConfig{{Name: "broken", Stage: "pre", Command: helperCommand(t, "fail")}}
+	trans, _ := NewTranslator(cfg)
+
+	_, err := trans.Translate("hello", "zh")
+	if err == nil || !strings.Contains(err.Error(), "hook broken") || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("request was sent despite failing hook")
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	tests := []struct {
+		hook    HookConfig
+		wantErr bool
+	}{
+		{HookConfig{Stage: "pre", Pattern: `\d+`, Replace: "N"}, false},
+		{HookConfig{Stage: "post", Command: []string{"cat"}, OnError: "skip"}, false},
+		{HookConfig{Stage: "during", Pattern: "x"}, true},
+		{HookConfig{Stage: "pre"}, true},
+		{HookConfig{Stage: "pre", Pattern: "("}, true},
+		{HookConfig{Stage: "pre", Pattern: "x", Command: []string{"cat"}}, true},
+		{HookConfig{Stage: "pre", Pattern: "x", OnError: "retry"}, true},
+	}
+	for _, tt := range tests {
+		cfg := testConfig("http://localhost")
+		cfg.Hooks = []HookConfig{tt.hook}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.hook, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHooksCoverEveryMode(t *testing.T) {
+	const secret = "alice@example.com"
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "[EMAIL]" })
+	cfg := testConfig(srv.URL)
+	cfg.Hooks = []HookConfig{
+		{Name: "mask", Stage: "pre", Pattern: `alice@example\.com`, Replace: "[EMAIL]"},
+		{Name: "unmask", Stage: "post", Pattern: `\[EMAIL\]`, Replace: secret},
+	}
+	cfg.Verify.Enabled = true
+	cfg.ContextWindow.Enabled = true
+	cfg.Cache.Enabled = false
+	trans, _ := NewTranslator(cfg)
+
+	var streamed bytes.Buffer
+	for mode, run := range map[string]func() error{
+		"verify": func() error { _, err := trans.Translate("Mail "+secret, "zh"); return err },
+		"stream": func() error {
+			_, err := trans.TranslateStream(NewParagraphScanner(strings.NewReader("Mail "+secret+"\n\nThanks")), &streamed, "zh")
+			return err
+		},
+		"alternatives": func() error { _, err := trans.Alternatives("Mail "+secret, "zh", 2); return err },
+		"explain":      func() error { _, err := trans.Explain("Mail "+secret, "zh"); return err },
+		"html":         func() error { _, _, err := trans.TranslateHTML("<p>Mail <a>"+secret+"</a></p>", "zh"); return err },
+	} {
+		*requests = nil
+		run()
+		if len(*requests) == 0 {
+			t.Errorf("%s: no request sent", mode)
+		}
+		for _, req := range *requests {
+			body, _ := json.Marshal(req)
+			if strings.Contains(string(body), secret) {
+				t.Errorf("%s: email was sent to the API: %s", mode, body)
+			}
+		}
+	}
+	if got, want := streamed.String(), secret+"\n\n"+secret; got != want {
+		t.Errorf("streamed %q, want %q", got, want)
+	}
+
+	if _, err := trans.Lookup("hello", "zh"); err == nil {
+		t.Error("dictionary lookup ran with hooks configured")
+	}
+}
+
+func TestHooksAcrossFailover(t *testing.T) {
+	const secret = "alice@example.com"
+	var downCalls atomic.Int32
+	down := newStatusServer(t, http.StatusBadGateway, &downCalls)
+	healthy, requests := newTestServer(t, func(ChatRequest) string { return "[EMAIL]" })
+	cfg := testConfig(down.URL)
+	cfg.API.Fallbacks = []BackendConfig{{Name: "healthy", Endpoint: healthy.URL, Key: "k"}}
+	cfg.Hooks = []HookConfig{
+		{Stage: "pre", Pattern: `alice@example\.com`, Replace: "[EMAIL]"},
+		{Stage: "post", Pattern: `\[EMAIL\]`, Replace: secret},
+	}
+	cfg.Cache.Enabled = false
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.Translate(secret, "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Translations[0]; got.Text != secret || got.Backend != "healthy" || !got.Fallback {
+		t.Errorf("got %+v", got)
+	}
+	alternatives, err := trans.Alternatives(secret, "zh", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := alternatives.Languages[0]; got.Variants[0].Text != secret || got.Backend != "healthy" {
+		t.Errorf("got %+v", got)
+	}
+	if downCalls.Load() != 2 || len(*requests) != 2 {
+		t.Errorf("got %d requests to the primary and %d to the fallback, want 2 each", downCalls.Load(), len(*requests))
+	}
+}
@@ -0,0 +1,164 @@
+package src
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxImageBytes is the largest image sent to the API
+const maxImageBytes = 20 << 20
+
+// imageTokens is the rough token cost of an image, as charged for a
+// high-detail image of typical screenshot size
+const imageTokens = 765
+
+// imageTypes are the image formats vision models accept
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// textOnlyModelRe matches models known not to accept images
+var textOnlyModelRe = regexp.MustCompile(`^(?:.*/)?(?:gpt-3\.5|gpt-4(?:-\d{4}|-32k)?$|o1-mini|o3-mini|deepseek-|text-)`)
+
+// defaultImageTemplate asks for the text visible in an image with its
+// translation
+const defaultImageTemplate = `Extract all visible text from the image in reading order, one entry per line of text,
+and translate each line to {language}. Keep numbers, code and names unchanged. Reply with JSON only, in the form:
+{"lines": [{"original": "...", "translation": "..."}]}
+Reply with {"lines": []} if the image contains no text.`
+
+// ImageLine is a line of text found in an image with its translation
+type ImageLine struct {
+	Original    string `json:"original"`
+	Translation string `json:"translation"`
+}
+
+// ImageTranslation holds the text of an image translated to one language
+type ImageTranslation struct {
+	Language string       `json:"language"`
+	Lines    []*ImageLine `json:"lines"`
+	Backend  string       `json:"backend,omitempty"`
+}
+
+// ImageResult holds the translations of the text in an image
+type ImageResult struct {
+	Image        string              `json:"image"`
+	Translations []*ImageTranslation `json:"translations"`
+}
+
+// imageContent reads an image file into a base64 data URL part
+func imageContent(path string) (ContentPart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return ContentPart{}, fmt.Errorf("image %s is larger than %d MB", path, maxImageBytes>>20)
+	}
+	mediaType := http.DetectContentType(data)
+	if !imageTypes[mediaType] {
+		return ContentPart{}, fmt.Errorf("unsupported image type %s (use PNG, JPEG, GIF or WebP)", mediaType)
+	}
+	url := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}, nil
+}
+
+// supportsVision reports whether the model accepts images: as configured
+// with api.vision, or unless it is a known text-only model
+func (c *Client) supportsVision() bool {
+	if c.config.API.Vision != nil {
+		return *c.config.API.Vision
+	}
+	model := c.config.API.Model
+	if model == "" {
+		model = c.config.API.Deployment
+	}
+	return !textOnlyModelRe.MatchString(strings.ToLower(model))
+}
+
+// TranslateImage asks a vision model for the text in image and its
+// translation
+func (c *Client) TranslateImage(image ContentPart, targetLanguage string) (*ImageTranslation, error) {
+	if !c.supportsVision() {
+		return nil, &retryableError{fmt.Errorf("model %s does not accept images, use a vision model such as gpt-4o (or set api.vision: true)", c.Name())}
+	}
+	template := c.config.Advanced.ImageTemplate
+	if template == "" {
+		template = defaultImageTemplate
+	}
+	messages := append(c.systemMessages("", targetLanguage), Message{
+		Role:    "user",
+		Content: PartsContent(ContentPart{Type: "text", Text: c.renderTemplate(template, "", targetLanguage)}, image),
+	})
+
+	resp, err := c.Chat(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImageTranslation{Language: targetLanguage}
+	if err := parseJSONAnswer(resp.Choices[0].Message.Content.Text, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TranslateImage translates the text visible in the image file at path to
+// the target language(s)
+func (t *Translator) TranslateImage(path, targetLang string) (*ImageResult, error) {
+	image, err := imageContent(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImageResult{Image: filepath.Base(path)}
+	for _, lang := range t.targetLanguages(targetLang) {
+		t.logger.Debug("calling API", "lang", lang, "image", path)
+		var translation *ImageTranslation
+		client, err := t.failover(func(c *Client) error {
+			var err error
+			translation, err = c.TranslateImage(image, lang)
+			return err
+		})
+		if err != nil {
+			if targetLang != "" {
+				return nil, fmt.Errorf("translation failed: %w", err)
+			}
+			t.logger.Error("failed to translate", "lang", lang, "err", err)
+			continue
+		}
+		translation.Backend = client.Name()
+		result.Translations = append(result.Translations, translation)
+	}
+
+	if len(result.Translations) == 0 {
+		return nil, fmt.Errorf("failed to translate to any language")
+	}
+	return result, nil
+}
+
+// Format renders the lines of the image with their translations
+func (r *ImageResult) Format(color bool) string {
+	var b strings.Builder
+	b.WriteString(c("Image", colorGray+colorBold, color) + ": " + r.Image)
+	for _, tr := range r.Translations {
+		b.WriteString("\n" + c(strings.Repeat("-", 6), colorGray, color))
+		b.WriteString("\n" + c(getLanguageName(tr.Language)+":", colorGreen+colorBold, color))
+		if len(tr.Lines) == 0 {
+			b.WriteString(" " + c("no text found", colorDim, color))
+		}
+		for _, line := range tr.Lines {
+			b.WriteString("\n  " + c(line.Original, colorDim, color))
+			b.WriteString("\n  " + c("→", colorMagenta, color) + " " + line.Translation)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentJSON(t *testing.T) {
+	data, _ := json.Marshal(Message{Role: "user", Content: TextContent("hi")})
+	if string(data) != `{"role":"user","content":"hi"}` {
+		t.Errorf("text content encoded as %s", data)
+	}
+
+	m := Message{Role: "user", Content: PartsContent(
+		ContentPart{Type: "text", Text: "read this"},
+		ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,AA=="}},
+	)}
+	data, _ = json.Marshal(m)
+	want := `{"role":"user","content":[{"type":"text","text":"read this"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AA=="}}]}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Content.Text != "read this" || len(decoded.Content.Parts) != 2 {
+		t.Errorf("unexpected decoded content: %+v", decoded.Content)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null}`), &decoded); err != nil || decoded.Content.Text != "" {
+		t.Errorf("null content: %+v, %v", decoded.Content, err)
+	}
+}
+
+// writePNG writes a small PNG image and returns its path
+func writePNG(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "screenshot.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTranslateImage(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string {
+		return "```json\n" + `{"lines": [{"original": "保存", "translation": "Save"}, {"original": "取消", "translation": "Cancel"}]}` + "\n```"
+	})
+	cfg := testConfig(srv.URL)
+	cfg.API.Model = "gpt-4o"
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.TranslateImage(writePNG(t), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Image != "screenshot.png" || len(result.Translations) != 1 || len(result.Translations[0].Lines) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if line := result.Translations[0].Lines[1]; line.Original != "取消" || line.Translation != "Cancel" {
+		t.Errorf("unexpected line: %+v", line)
+	}
+
+	parts := (*requests)[0].Messages[len((*requests)[0].Messages)-1].Content.Parts
+	if len(parts) != 2 || parts[1].ImageURL == nil || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("image not sent as a data URL part: %+v", parts)
+	}
+	if !strings.Contains(parts[0].Text, "English") {
+		t.Errorf("prompt does not name the target language: %q", parts[0].Text)
+	}
+
+	want := "Image: screenshot.png\n------\nEnglish:\n  保存\n  → Save\n  取消\n  → Cancel"
+	if got := result.Format(false); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTranslateImageRequiresVisionModel(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string { return `{"lines": []}` })
+	trans, _ := NewTranslator(testConfig(srv.URL))
+	path := writePNG(t)
+
+	if _, err := trans.TranslateImage(path, "en"); err == nil || !strings.Contains(err.Error(), "does not accept images") {
+		t.Errorf("got %v, want an error for the text-only model gpt-4", err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("sent %d requests to a text-only model", len(*requests))
+	}
+
+	vision := true
+	trans.config.API.Vision = &vision
+	if _, err := trans.TranslateImage(path, "en"); err != nil {
+		t.Errorf("api.vision did not override the model check: %v", err)
+	}
+
+	text := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(text, []byte("not an image"), 0o644)
+	if _, err := trans.TranslateImage(text, "en"); err == nil {
+		t.Error("expected error for a file that is not an image")
+	}
+}
+
+func TestTranslateImageFailsOverToVisionModel(t *testing.T) {
+	primary, primaryRequests := newTestServer(t, func(ChatRequest) string { return `{"lines": []}` })
+	vision, _ := newTestServer(t, func(ChatRequest) string {
+		return `{"lines": [{"original": "保存", "translation": "Save"}]}`
+	})
+	cfg := testConfig(primary.URL)
+	cfg.API.Model = "gpt-3.5-turbo"
+	cfg.API.Fallbacks = []BackendConfig{{Name: "vision", Endpoint: vision.URL, Key: "k", Model: "gpt-4o"}}
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.TranslateImage(writePNG(t), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Translations[0]; got.Backend != "vision" || len(got.Lines) != 1 {
+		t.Errorf("unexpected translation: %+v", got)
+	}
+	if len(*primaryRequests) != 0 {
+		t.Errorf("sent %d requests to the text-only primary", len(*primaryRequests))
+	}
+}
@@ -0,0 +1,212 @@
+package src
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineBytes is the longest input line accepted; longer lines usually mean
+// minified or binary data
+const maxLineBytes = 1 << 20
+
+var (
+	// ErrBinaryInput is returned for input that is not UTF-8 text
+	ErrBinaryInput = errors.New("input looks like binary data, not UTF-8 text")
+
+	// ErrLineTooLong is returned for lines longer than maxLineBytes
+	ErrLineTooLong = fmt.Errorf("line longer than %d bytes", maxLineBytes)
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Chunk is a paragraph together with the exact whitespace preceding it.
+// The last chunk of a stream may have an empty Text and carry only the
+// trailing whitespace.
+type Chunk struct {
+	Separator string
+	Text      string
+}
+
+// ParagraphScanner splits a text stream into paragraphs separated by blank
+// lines without limiting the line length to bufio.Scanner's 64 KiB. A UTF-8
+// BOM is dropped and CRLF line endings are normalised to LF in paragraph
+// text; separators are kept verbatim.
+type ParagraphScanner struct {
+	r     *bufio.Reader
+	line  int
+	crlf  bool
+	carry string
+	peek  *string
+	queue []Chunk
+	err   error
+}
+
+// NewParagraphScanner creates a scanner reading from r
+func NewParagraphScanner(r io.Reader) *ParagraphScanner {
+	return &ParagraphScanner{r: bufio.NewReader(r)}
+}
+
+// CRLF reports whether the input uses CRLF line endings
+func (s *ParagraphScanner) CRLF() bool {
+	return s.crlf
+}
+
+// RestoreLineEndings converts LF to the line endings of the input
+func (s *ParagraphScanner) RestoreLineEndings(text string) string {
+	if !s.crlf {
+		return text
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// MultiParagraph reads ahead and reports whether the input has more than one
+// paragraph
+func (s *ParagraphScanner) MultiParagraph() (bool, error) {
+	paragraphs := 0
+	for i := 0; paragraphs < 2; i++ {
+		if i == len(s.queue) {
+			chunk, err := s.scan()
+			if err == io.EOF {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			s.queue = append(s.queue, chunk)
+		}
+		if s.queue[i].Text != "" {
+			paragraphs++
+		}
+	}
+	return true, nil
+}
+
+// ReadAll returns the remaining input as one string with LF line endings
+func (s *ParagraphScanner) ReadAll() (string, error) {
+	var b strings.Builder
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			return strings.ReplaceAll(b.String(), "\r\n", "\n"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(chunk.Separator)
+		b.WriteString(chunk.Text)
+	}
+}
+
+// Next returns the next chunk, or io.EOF at the end of the input
+func (s *ParagraphScanner) Next() (Chunk, error) {
+	if len(s.queue) > 0 {
+		chunk := s.queue[0]
+		s.queue = s.queue[1:]
+		return chunk, nil
+	}
+	return s.scan()
+}
+
+func (s *ParagraphScanner) scan() (Chunk, error) {
+	sep := s.carry
+	s.carry = ""
+
+	// Blank lines belong to the separator
+	for {
+		line, err := s.readLine()
+		if err == io.EOF {
+			if sep == "" {
+				return Chunk{}, io.EOF
+			}
+			return Chunk{Separator: sep}, nil
+		}
+		if err != nil {
+			return Chunk{}, err
+		}
+		if strings.TrimSpace(line) != "" {
+			s.unread(line)
+			break
+		}
+		sep += line
+	}
+
+	// Non-blank lines form the paragraph
+	var text strings.Builder
+	for {
+		line, err := s.readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Chunk{}, err
+		}
+		if strings.TrimSpace(line) == "" {
+			s.unread(line)
+			break
+		}
+		text.WriteString(line)
+	}
+
+	// The final line ending is part of the next separator
+	body := text.String()
+	trimmed := strings.TrimRight(body, "\r\n")
+	s.carry = body[len(trimmed):]
+	return Chunk{Separator: sep, Text: strings.ReplaceAll(trimmed, "\r\n", "\n")}, nil
+}
+
+func (s *ParagraphScanner) unread(line string) {
+	s.peek = &line
+}
+
+// readLine returns the next line including its line ending
+func (s *ParagraphScanner) readLine() (string, error) {
+	if s.peek != nil {
+		line := *s.peek
+		s.peek = nil
+		return line, nil
+	}
+	if s.err != nil {
+		return "", s.err
+	}
+
+	var buf []byte
+	for {
+		frag, err := s.r.ReadSlice('\n')
+		buf = append(buf, frag...)
+		if len(buf) > maxLineBytes {
+			s.err = fmt.Errorf("line %d: %w", s.line+1, ErrLineTooLong)
+			return "", s.err
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF || len(buf) == 0 {
+				s.err = err
+				return "", err
+			}
+			s.err = io.EOF
+		}
+		break
+	}
+
+	s.line++
+	if s.line == 1 {
+		if bytes.HasPrefix(buf, []byte{0xFE, 0xFF}) || bytes.HasPrefix(buf, []byte{0xFF, 0xFE}) {
+			s.err = fmt.Errorf("UTF-16 input is not supported, convert it to UTF-8 first")
+			return "", s.err
+		}
+		buf = bytes.TrimPrefix(buf, utf8BOM)
+		s.crlf = bytes.HasSuffix(buf, []byte("\r\n"))
+	}
+	if bytes.IndexByte(buf, 0) >= 0 || !utf8.Valid(buf) {
+		s.err = fmt.Errorf("line %d: %w", s.line, ErrBinaryInput)
+		return "", s.err
+	}
+	return string(buf), nil
+}
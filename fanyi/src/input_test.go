@@ -0,0 +1,104 @@
+package src
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func scanAll(t *testing.T, input string) ([]Chunk, *ParagraphScanner) {
+	t.Helper()
+	sc := NewParagraphScanner(strings.NewReader(input))
+	var chunks []Chunk
+	for {
+		chunk, err := sc.Next()
+		if err == io.EOF {
+			return chunks, sc
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestParagraphScannerPreservesSpacing(t *testing.T) {
+	input := "\n  \nfirst line\n  indented\n\n\n\nsecond\n \n"
+	chunks, _ := scanAll(t, input)
+
+	want := []Chunk{
+		{Separator: "\n  \n", Text: "first line\n  indented"},
+		{Separator: "\n\n\n\n", Text: "second"},
+		{Separator: "\n \n"},
+	}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %q, want %q", chunks, want)
+	}
+	var rebuilt strings.Builder
+	for i, chunk := range chunks {
+		if chunk != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, chunk, want[i])
+		}
+		rebuilt.WriteString(chunk.Separator + chunk.Text)
+	}
+	if rebuilt.String() != input {
+		t.Errorf("rebuilt %q, want %q", rebuilt.String(), input)
+	}
+}
+
+func TestParagraphScannerBOMAndCRLF(t *testing.T) {
+	chunks, sc := scanAll(t, "\xEF\xBB\xBFone\r\ntwo\r\n\r\nthree")
+	if len(chunks) != 2 || chunks[0].Text != "one\ntwo" || chunks[1] != (Chunk{Separator: "\r\n\r\n", Text: "three"}) {
+		t.Fatalf("unexpected chunks %q", chunks)
+	}
+	if !sc.CRLF() || sc.RestoreLineEndings("a\nb") != "a\r\nb" {
+		t.Error("CRLF line endings not restored")
+	}
+}
+
+func TestParagraphScannerLongLines(t *testing.T) {
+	long := strings.Repeat("a", 100000)
+	chunks, _ := scanAll(t, long+"\n\nshort")
+	if len(chunks) != 2 || chunks[0].Text != long {
+		t.Fatalf("long line was not read in one piece")
+	}
+
+	sc := NewParagraphScanner(strings.NewReader(strings.Repeat("a", maxLineBytes+1)))
+	if _, err := sc.Next(); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("err = %v, want ErrLineTooLong", err)
+	}
+}
+
+func TestParagraphScannerBinary(t *testing.T) {
+	for _, input := range []string{"text\x00more", "ok\n\xff\xfe\xfd"} {
+		sc := NewParagraphScanner(strings.NewReader(input))
+		if _, err := sc.ReadAll(); !errors.Is(err, ErrBinaryInput) {
+			t.Errorf("ReadAll(%q) err = %v, want ErrBinaryInput", input, err)
+		}
+	}
+}
+
+func TestTranslateStream(t *testing.T) {
+	srv, _ := newTestServer(t, func(req ChatRequest) string {
+		last := req.Messages[len(req.Messages)-1].Content.Text
+		return strings.ToUpper(last[strings.LastIndex(last, "Text: ")+6:])
+	})
+	trans, _ := NewTranslator(testConfig(srv.URL))
+
+	sc := NewParagraphScanner(strings.NewReader("one\r\ntwo\r\n\r\n\r\nthree\r\n"))
+	if multi, err := sc.MultiParagraph(); err != nil || !multi {
+		t.Fatalf("MultiParagraph() = %v, %v", multi, err)
+	}
+	var out strings.Builder
+	lang, err := trans.TranslateStream(sc, &out, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang != "zh" {
+		t.Errorf("translated to %q, want the first priority language zh", lang)
+	}
+	if want := "ONE\r\nTWO\r\n\r\n\r\nTHREE\r\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
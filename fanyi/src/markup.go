@@ -0,0 +1,396 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxAttributeBatch limits the attribute values sent in one request
+const maxAttributeBatch = 20
+
+// skippedElements are never translated, including their descendants
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true,
+	atom.Style:  true,
+	atom.Code:   true,
+	atom.Pre:    true,
+}
+
+// translatableAttrs are the attributes whose values are translated
+var translatableAttrs = map[string]bool{
+	"alt":         true,
+	"title":       true,
+	"placeholder": true,
+}
+
+// blockElements start a new batch of text so that the model sees the
+// inline text of a block as one passage
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Body: true, atom.Button: true, atom.Caption: true, atom.Dd: true,
+	atom.Details: true, atom.Dialog: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Head: true, atom.Header: true, atom.Hgroup: true, atom.Html: true,
+	atom.Label: true, atom.Legend: true, atom.Li: true, atom.Main: true,
+	atom.Nav: true, atom.Ol: true, atom.Option: true, atom.P: true,
+	atom.Section: true, atom.Summary: true, atom.Table: true, atom.Tbody: true,
+	atom.Td: true, atom.Tfoot: true, atom.Th: true, atom.Thead: true,
+	atom.Title: true, atom.Tr: true, atom.Ul: true,
+}
+
+// segment is a piece of text in a document and how to replace it
+type segment struct {
+	text string
+	set  func(string)
+}
+
+// newSegment creates a segment for text, keeping its surrounding whitespace
+func newSegment(text string, set func(string)) segment {
+	core := strings.TrimSpace(text)
+	start := strings.Index(text, core)
+	lead, trail := text[:start], text[start+len(core):]
+	return segment{text: core, set: func(s string) { set(lead + s + trail) }}
+}
+
+// htmlCollector gathers the translatable text of an HTML tree
+type htmlCollector struct {
+	groups  [][]segment
+	attrs   []segment
+	current []segment
+}
+
+func (hc *htmlCollector) flush() {
+	if len(hc.current) > 0 {
+		hc.groups = append(hc.groups, hc.current)
+		hc.current = nil
+	}
+}
+
+func (hc *htmlCollector) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if strings.TrimSpace(n.Data) != "" {
+			hc.current = append(hc.current, newSegment(n.Data, func(s string) { n.Data = s }))
+		}
+		return
+	case html.ElementNode:
+		if skippedElements[n.DataAtom] || noTranslate(n.Attr) {
+			return
+		}
+		for i := range n.Attr {
+			attr := &n.Attr[i]
+			if attr.Namespace == "" && translatableAttrs[attr.Key] && strings.TrimSpace(attr.Val) != "" {
+				hc.attrs = append(hc.attrs, newSegment(attr.Val, func(s string) { attr.Val = s }))
+			}
+		}
+		if blockElements[n.DataAtom] {
+			hc.flush()
+			defer hc.flush()
+		}
+	case html.DocumentNode:
+	default:
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		hc.walk(child)
+	}
+}
+
+// noTranslate reports whether the attributes contain translate="no"
+func noTranslate(attrs []html.Attribute) bool {
+	for _, a := range attrs {
+		if a.Key == "translate" && strings.EqualFold(strings.TrimSpace(a.Val), "no") {
+			return true
+		}
+	}
+	return false
+}
+
+// TranslateHTML translates the text nodes and the alt, title and placeholder
+// attributes of an HTML document or fragment into targetLang, leaving the
+// markup and the content of script, style, code, pre and translate="no"
+// elements untouched. Text is sent in one request per block element. It
+// returns the document and the language it was translated to.
+func (t *Translator) TranslateHTML(input, targetLang string) (string, string, error) {
+	fullDocument := looksLikeHTMLDocument(input)
+
+	var nodes []*html.Node
+	if fullDocument {
+		doc, err := html.Parse(strings.NewReader(input))
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		nodes = []*html.Node{doc}
+	} else {
+		body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+		var err error
+		if nodes, err = html.ParseFragment(strings.NewReader(input), body); err != nil {
+			return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+		}
+	}
+
+	hc := &htmlCollector{}
+	for _, n := range nodes {
+		hc.walk(n)
+	}
+	hc.flush()
+
+	groups := hc.groups
+	for i := 0; i < len(hc.attrs); i += maxAttributeBatch {
+		groups = append(groups, hc.attrs[i:min(i+maxAttributeBatch, len(hc.attrs))])
+	}
+	lang, err := t.translateGroups(groups, targetLang)
+	if err != nil {
+		return "", "", err
+	}
+
+	var b bytes.Buffer
+	for _, n := range nodes {
+		if err := html.Render(&b, n); err != nil {
+			return "", "", fmt.Errorf("failed to render HTML: %w", err)
+		}
+	}
+	return b.String(), lang, nil
+}
+
+// looksLikeHTMLDocument reports whether input is a complete document rather
+// than a fragment
+func looksLikeHTMLDocument(input string) bool {
+	head := strings.ToLower(strings.TrimLeftFunc(input, unicode.IsSpace))
+	return strings.HasPrefix(head, "<!doctype") || strings.HasPrefix(head, "<html")
+}
+
+// TranslateXML translates the character data of an XML document into
+// targetLang. The document is edited in place, so everything except the
+// translated text is preserved byte for byte. Elements named script, style,
+// code or pre and elements with translate="no" are skipped. Elements inside
+// mixed content, such as <b> in <p>Click <b>here</b> to continue</p>, are
+// inline unless they are HTML block elements, and text is sent in one
+// request per block. It returns the document and the language it was
+// translated to.
+func (t *Translator) TranslateXML(input, targetLang string) (string, string, error) {
+	type token struct {
+		tok        xml.Token
+		start, end int64
+	}
+	type replacement struct {
+		start, end int64
+		text       string
+	}
+
+	// Read the document first to find the elements with mixed content
+	var (
+		tokens []token
+		mixed  []bool
+		open   []int
+	)
+	d := xml.NewDecoder(strings.NewReader(input))
+	for {
+		start := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse XML: %w", err)
+		}
+		tokens = append(tokens, token{xml.CopyToken(tok), start, d.InputOffset()})
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			open = append(open, len(mixed))
+			mixed = append(mixed, false)
+		case xml.EndElement:
+			if len(open) == 0 {
+				return "", "", fmt.Errorf("failed to parse XML: unexpected end element %s", tok.Name.Local)
+			}
+			open = open[:len(open)-1]
+		case xml.CharData:
+			if len(open) > 0 && strings.TrimSpace(string(tok)) != "" {
+				mixed[open[len(open)-1]] = true
+			}
+		}
+	}
+
+	type element struct {
+		skip, block bool
+	}
+	var (
+		replacements []*replacement
+		groups       [][]segment
+		current      []segment
+		stack        []element
+		n            int
+	)
+	open = open[:0]
+	flush := func() {
+		if len(current) > 0 {
+			groups = append(groups, current)
+			current = nil
+		}
+	}
+	for _, tk := range tokens {
+		switch tok := tk.tok.(type) {
+		case xml.StartElement:
+			var parent element
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			name := atom.Lookup([]byte(strings.ToLower(tok.Name.Local)))
+			el := element{skip: parent.skip || skippedElements[name]}
+			for _, a := range tok.Attr {
+				if a.Name.Local == "translate" && strings.EqualFold(a.Value, "no") {
+					el.skip = true
+				}
+			}
+			parentMixed := len(stack) > 0 && mixed[open[len(open)-1]]
+			el.block = !parentMixed || blockElements[name]
+			if el.block {
+				flush()
+			}
+			stack = append(stack, el)
+			open = append(open, n)
+			n++
+		case xml.EndElement:
+			if stack[len(stack)-1].block {
+				flush()
+			}
+			stack, open = stack[:len(stack)-1], open[:len(open)-1]
+		case xml.CharData:
+			if len(stack) == 0 || stack[len(stack)-1].skip || strings.TrimSpace(string(tok)) == "" {
+				continue
+			}
+			r := &replacement{start: tk.start, end: tk.end}
+			replacements = append(replacements, r)
+			current = append(current, newSegment(string(tok), func(s string) {
+				r.text = xmlTextEscaper.Replace(s)
+			}))
+		}
+	}
+	flush()
+
+	lang, err := t.translateGroups(groups, targetLang)
+	if err != nil {
+		return "", "", err
+	}
+
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start < replacements[j].start })
+	var b strings.Builder
+	var pos int64
+	for _, r := range replacements {
+		if r.text == "" {
+			continue
+		}
+		b.WriteString(input[pos:r.start])
+		b.WriteString(r.text)
+		pos = r.end
+	}
+	b.WriteString(input[pos:])
+	return b.String(), lang, nil
+}
+
+// xmlTextEscaper escapes translated XML text. Unlike xml.EscapeText it
+// keeps line breaks and tabs as they are.
+var xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;")
+
+// translateGroups translates each group of segments in one request and
+// replaces the segment text with the translation. Each segment passes
+// through the hooks on its own. Without lang the document is translated to
+// the first priority language that differs from its own; the language used
+// is returned.
+func (t *Translator) translateGroups(groups [][]segment, lang string) (string, error) {
+	if lang == "" {
+		var b strings.Builder
+		for _, group := range groups {
+			for _, seg := range group {
+				b.WriteString(seg.text + "\n")
+			}
+		}
+		lang = t.defaultTarget(b.String())
+	}
+
+	for i, group := range groups {
+		texts := make([]string, len(group))
+		for j, seg := range group {
+			texts[j] = seg.text
+		}
+
+		var translations []string
+		_, err := t.hooked(texts, lang, func(c *Client, texts []string) ([][]*string, error) {
+			t.logger.Debug("calling API", "lang", lang, "block", i+1, "total", len(groups), "backend", c.Name())
+			var err error
+			if translations, err = c.TranslateSegments(texts, lang); err != nil {
+				return nil, err
+			}
+			outs := make([][]*string, len(translations))
+			for j := range translations {
+				outs[j] = []*string{&translations[j]}
+			}
+			return outs, nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("block %d: %w", i+1, err)
+		}
+		for j, seg := range group {
+			seg.set(translations[j])
+		}
+	}
+	return lang, nil
+}
+
+// TranslateSegments translates consecutive pieces of one passage, such as the
+// text nodes of an HTML paragraph, returning one translation per piece. If the
+// model does not keep the pieces apart they are translated one by one.
+func (c *Client) TranslateSegments(segments []string, targetLanguage string) ([]string, error) {
+	if len(segments) == 1 {
+		translation, err := c.Translate(segments[0], targetLanguage)
+		if err != nil {
+			return nil, err
+		}
+		return []string{translation}, nil
+	}
+
+	input, err := json.Marshal(segments)
+	if err != nil {
+		return nil, err
+	}
+	prompt := fmt.Sprintf(`You are a professional translator. The JSON array below holds consecutive pieces of one passage,
+split where inline markup such as links or emphasis begins or ends. Translate the passage to %s,
+distributing the translation over the same number of pieces in the same order so the markup can be restored.
+Reply with JSON only, in the form {"segments": ["...", "..."]} with exactly %d strings.
+
+%s`, getLanguageName(targetLanguage), len(segments), input)
+
+	messages := append(c.systemMessages(string(input), targetLanguage), Message{Role: "user", Content: TextContent(prompt)})
+	resp, err := c.Chat(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer struct {
+		Segments []string `json:"segments"`
+	}
+	if err := parseJSONAnswer(resp.Choices[0].Message.Content.Text, &answer); err == nil && len(answer.Segments) == len(segments) {
+		return answer.Segments, nil
+	}
+
+	// Fall back to translating the pieces separately
+	translations := make([]string, len(segments))
+	for i, s := range segments {
+		if translations[i], err = c.Translate(s, targetLanguage); err != nil {
+			return nil, err
+		}
+	}
+	return translations, nil
+}
@@ -0,0 +1,134 @@
+package src
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newUpperServer answers translation requests with the upper-cased input,
+// keeping segment batches apart
+func newUpperServer(t *testing.T) (*Translator, *[]ChatRequest) {
+	t.Helper()
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		if i := strings.LastIndex(prompt, "\n["); i >= 0 {
+			var segments []string
+			json.Unmarshal([]byte(prompt[i+1:]), &segments)
+			for j := range segments {
+				segments[j] = strings.ToUpper(segments[j])
+			}
+			data, _ := json.Marshal(map[string][]string{"segments": segments})
+			return string(data)
+		}
+		return strings.ToUpper(prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):])
+	})
+	trans, _ := NewTranslator(testConfig(srv.URL))
+	return trans, requests
+}
+
+func TestTranslateHTML(t *testing.T) {
+	trans, requests := newUpperServer(t)
+
+	input := `<h1 title="greeting">Hello</h1>
+<p>Click <a href="/x">this link</a> now.<img src="a.png" alt="a cat"></p>
+<pre>keep me</pre><p translate="no">brand</p><script>var s = "x";</script>
+<input placeholder="your name"><p>Use <code>go run</code> &amp; relax</p>`
+	got, _, err := trans.TranslateHTML(input, "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<h1 title="GREETING">HELLO</h1>
+<p>CLICK <a href="/x">THIS LINK</a> NOW.<img src="a.png" alt="A CAT"/></p>
+<pre>keep me</pre><p translate="no">brand</p><script>var s = "x";</script>
+<input placeholder="YOUR NAME"/><p>USE <code>go run</code> &amp; RELAX</p>`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	// h1, the two paragraphs and one batch of attributes
+	if len(*requests) != 4 {
+		t.Errorf("got %d requests, want 4", len(*requests))
+	}
+}
+
+func TestTranslateHTMLDocument(t *testing.T) {
+	trans, _ := newUpperServer(t)
+
+	got, lang, err := trans.TranslateHTML("<!DOCTYPE html><html><head><title>Help</title><style>p{}</style></head><body><p>Hi</p></body></html>", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang != "zh" {
+		t.Errorf("translated to %q, want the first priority language zh", lang)
+	}
+	if want := "<!DOCTYPE html><html><head><title>HELP</title><style>p{}</style></head><body><p>HI</p></body></html>"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestTranslateXML(t *testing.T) {
+	trans, requests := newUpperServer(t)
+
+	input := `<?xml version="1.0"?>
+<!-- help -->
+<doc id="1">
+  <title>Tom &amp; Jerry</title>
+  <para>Say <em>hi</em> to <![CDATA[them]]></para>
+  <code>x &lt; y</code>
+  <para translate="no">brand</para>
+  <para>First line
+	second "line"</para>
+</doc>
+`
+	got, _, err := trans.TranslateXML(input, "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0"?>
+<!-- help -->
+<doc id="1">
+  <title>TOM &amp; JERRY</title>
+  <para>SAY <em>HI</em> TO THEM</para>
+  <code>x &lt; y</code>
+  <para translate="no">brand</para>
+  <para>FIRST LINE
+	SECOND &#34;LINE&#34;</para>
+</doc>
+`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	// The title and the two paragraphs, with <em> in its paragraph
+	if len(*requests) != 3 {
+		t.Errorf("got %d requests, want 3", len(*requests))
+	}
+}
+
+func TestTranslateXMLMixedContent(t *testing.T) {
+	trans, requests := newUpperServer(t)
+
+	input := `<help><p>Click <b>here</b> to continue</p><note>Text <p>Block</p> more</note></help>`
+	got, _, err := trans.TranslateXML(input, "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<help><p>CLICK <b>HERE</b> TO CONTINUE</p><note>TEXT <p>BLOCK</p> MORE</note></help>`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	// The inline <b> is sent with its paragraph; the <p> inside the note is a
+	// block of its own
+	var texts []string
+	for _, req := range *requests {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		if i := strings.LastIndex(prompt, "\n["); i >= 0 {
+			texts = append(texts, prompt[i+1:])
+		} else {
+			texts = append(texts, prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):])
+		}
+	}
+	want := []string{`["Click","here","to continue"]`, `Text`, `Block`, `more`}
+	if strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("got requests %q, want %q", texts, want)
+	}
+}
@@ -0,0 +1,215 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultPromptTemplate is used when no prompt template is configured
+const defaultPromptTemplate = `You are a professional translator. Translate the following text to {language}.
+Only return the translated text without any explanation or additional content.
+
+Text: {input_text}`
+
+// defaultAlternativesTemplate asks for one translation variant with a usage note
+const defaultAlternativesTemplate = `You are a professional translator. Translate the following text to {language}.
+Choose the rendering you find most natural, then briefly note when it is the best choice
+(register, tone, typical context). Reply with JSON only, in the form:
+{"translation": "...", "note": "..."}
+
+Text: {input_text}`
+
+// defaultExplainTemplate asks for a translation with a word-by-word gloss
+const defaultExplainTemplate = `You are a language teacher. Translate the following text to {language} and explain it.
+Give a word-by-word (or phrase-by-phrase) gloss in source order, a short note on the grammar,
+and an explanation of any idioms. Write the notes in {language}. Reply with JSON only, in the form:
+{"translation": "...", "gloss": [{"source": "...", "target": "...", "note": "..."}], "grammar": "...", "idioms": "..."}
+
+Text: {input_text}`
+
+// Example represents a few-shot example pair for a target language
+type Example struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+// stylePresets are the built-in translation styles selectable with --style
+var stylePresets = map[string]string{
+	"formal":    "Use a formal, polite register suitable for official documents and business correspondence.",
+	"casual":    "Use a casual, conversational register as a native speaker would in everyday chat.",
+	"technical": "Use precise technical terminology, keep identifiers, code and units unchanged, and prefer established industry terms.",
+	"marketing": "Use persuasive, engaging marketing language that reads naturally to the target audience rather than literally.",
+}
+
+// templateVars lists the variables that may appear in prompt templates
+var templateVars = map[string]bool{
+	"language":        true,
+	"source_language": true,
+	"input_text":      true,
+	"glossary":        true,
+	"context":         true,
+}
+
+var placeholderRe = regexp.MustCompile(`\{([a-zA-Z_]+)\}`)
+
+// validateTemplate checks that a template only uses known variables
+func validateTemplate(name, template string, requireInput bool) error {
+	for _, m := range placeholderRe.FindAllStringSubmatch(template, -1) {
+		if !templateVars[m[1]] {
+			return fmt.Errorf("%s: unknown template variable {%s}", name, m[1])
+		}
+	}
+	if requireInput && !strings.Contains(template, "{input_text}") {
+		return fmt.Errorf("%s: template must contain {input_text}", name)
+	}
+	return nil
+}
+
+// StyleNames returns the names of all available style presets
+func (c *Config) StyleNames() []string {
+	var names []string
+	for name := range stylePresets {
+		names = append(names, name)
+	}
+	for name := range c.Advanced.Styles {
+		if _, ok := stylePresets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// styleInstruction returns the instruction for the configured style
+func (c *Config) styleInstruction() (string, error) {
+	style := c.Advanced.Style
+	if style == "" {
+		return "", nil
+	}
+	if s, ok := c.Advanced.Styles[style]; ok {
+		return s, nil
+	}
+	if s, ok := stylePresets[style]; ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown style %q (available: %s)", style, strings.Join(c.StyleNames(), ", "))
+}
+
+// validatePrompt checks the prompt templates, system prompt and style
+func (c *Config) validatePrompt() error {
+	templates := []struct{ name, template string }{
+		{"prompt_template", c.Advanced.PromptTemplate},
+		{"alternatives_template", c.Advanced.AlternativesTemplate},
+		{"explain_template", c.Advanced.ExplainTemplate},
+	}
+	for _, t := range templates {
+		if t.template == "" {
+			continue
+		}
+		if err := validateTemplate(t.name, t.template, true); err != nil {
+			return err
+		}
+	}
+	if err := validateTemplate("system_prompt", c.Advanced.SystemPrompt, false); err != nil {
+		return err
+	}
+	if err := validateTemplate("image_template", c.Advanced.ImageTemplate, false); err != nil {
+		return err
+	}
+	if _, err := c.styleInstruction(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// renderTemplate substitutes the template variables
+func (c *Client) renderTemplate(template, text, targetLanguage string) string {
+	source := "the original language"
+	if c.config.Languages.Source != "" {
+		source = getLanguageName(c.config.Languages.Source)
+	}
+	r := strings.NewReplacer(
+		"{language}", getLanguageName(targetLanguage),
+		"{source_language}", source,
+		"{glossary}", formatGlossary(c.config.Advanced.Glossary),
+		"{context}", c.config.Advanced.Context,
+		"{input_text}", text,
+	)
+	return r.Replace(template)
+}
+
+// buildMessages builds the system message, few-shot examples and user prompt
+func (c *Client) buildMessages(text, targetLanguage string) []Message {
+	messages := c.systemMessages(text, targetLanguage)
+
+	for _, ex := range c.config.Advanced.Examples[targetLanguage] {
+		messages = append(messages,
+			Message{Role: "user", Content: TextContent(c.buildPrompt(ex.Source, targetLanguage))},
+			Message{Role: "assistant", Content: TextContent(ex.Target)},
+		)
+	}
+
+	return append(messages, Message{Role: "user", Content: TextContent(c.buildPrompt(text, targetLanguage))})
+}
+
+// buildTemplateMessages builds the system message and a user prompt from
+// template, falling back to fallback when template is empty
+func (c *Client) buildTemplateMessages(template, fallback, text, targetLanguage string) []Message {
+	if template == "" {
+		template = fallback
+	}
+	messages := c.systemMessages(text, targetLanguage)
+	return append(messages, Message{Role: "user", Content: TextContent(c.renderTemplate(template, text, targetLanguage))})
+}
+
+// systemMessages returns the system message combining system prompt and style
+func (c *Client) systemMessages(text, targetLanguage string) []Message {
+	system := c.renderTemplate(c.config.Advanced.SystemPrompt, text, targetLanguage)
+	if style, _ := c.config.styleInstruction(); style != "" {
+		system = strings.TrimSpace(system + "\n\n" + style)
+	}
+	if system == "" {
+		return nil
+	}
+	return []Message{{Role: "system", Content: TextContent(system)}}
+}
+
+// parseJSONAnswer decodes a JSON object from a model answer, tolerating
+// surrounding prose and Markdown code fences
+func parseJSONAnswer(answer string, v any) error {
+	data, err := extractJSON(answer)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON in answer: %w", err)
+	}
+	return nil
+}
+
+// extractJSON returns the outermost JSON object in a model answer
+func extractJSON(answer string) ([]byte, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in answer %q", answer)
+	}
+	return []byte(answer[start : end+1]), nil
+}
+
+// formatGlossary renders glossary entries one per line
+func formatGlossary(glossary map[string]string) string {
+	terms := make([]string, 0, len(glossary))
+	for term := range glossary {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	var b strings.Builder
+	for _, term := range terms {
+		fmt.Fprintf(&b, "- %s => %s\n", term, glossary[term])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,66 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{"Translate to {language}: {input_text}", false},
+		{"{source_language} -> {language}\n{glossary}\n{context}\n{input_text}", false},
+		{"Translate to {lang}: {input_text}", true},
+		{"Translate to {language}", true},
+	}
+	for _, tt := range tests {
+		err := validateTemplate("prompt_template", tt.template, true)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateTemplate(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateUnknownStyle(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.API.Key = "test"
+	cfg.Advanced.Style = "poetic"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for unknown style")
+	}
+	cfg.Advanced.Styles = map[string]string{"poetic": "Translate like a poet."}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("custom style rejected: %v", err)
+	}
+}
+
+func TestBuildMessages(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Advanced.SystemPrompt = "You translate {context} into {language}."
+	cfg.Advanced.Context = "UI strings"
+	cfg.Advanced.Style = "formal"
+	cfg.Advanced.Glossary = map[string]string{"cart": "购物车"}
+	cfg.Advanced.PromptTemplate = "Glossary:\n{glossary}\nText: {input_text}"
+	cfg.Advanced.Examples = map[string][]Example{
+		"zh": {{Source: "Checkout", Target: "结账"}},
+	}
+
+	messages := NewClient(cfg).buildMessages("Add to cart", "zh")
+	if len(messages) != 4 {
+		t.Fatalf("got %d messages, want 4", len(messages))
+	}
+	if messages[0].Role != "system" || !strings.HasPrefix(messages[0].Content.Text, "You translate UI strings into Chinese.") {
+		t.Errorf("unexpected system message: %q", messages[0].Content.Text)
+	}
+	if !strings.Contains(messages[0].Content.Text, stylePresets["formal"]) {
+		t.Errorf("style instruction missing from system message")
+	}
+	if messages[2].Role != "assistant" || messages[2].Content.Text != "结账" {
+		t.Errorf("unexpected example answer: %+v", messages[2])
+	}
+	if want := "Glossary:\n- cart => 购物车\nText: Add to cart"; messages[3].Content.Text != want {
+		t.Errorf("user prompt = %q, want %q", messages[3].Content.Text, want)
+	}
+}
@@ -0,0 +1,95 @@
+package src
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// API providers, selecting the URL layout and authentication
+const (
+	ProviderOpenAI = "openai"
+	ProviderAzure  = "azure"
+)
+
+// defaultAzureAPIVersion is used when api_version is not configured
+const defaultAzureAPIVersion = "2024-06-01"
+
+// validateProvider checks the provider specific settings
+func (api *APIConfig) validateProvider() error {
+	switch api.Provider {
+	case "", ProviderOpenAI:
+		if api.Model == "" {
+			return fmt.Errorf("API model is required")
+		}
+	case ProviderAzure:
+		if api.Model == "" && api.Deployment == "" {
+			return fmt.Errorf("api.deployment or api.model is required for Azure")
+		}
+	default:
+		return fmt.Errorf("unknown API provider %q (available: %s, %s)", api.Provider, ProviderOpenAI, ProviderAzure)
+	}
+	return nil
+}
+
+// requestModel returns the model name sent in the request body, or "" to
+// omit it. Azure selects the model by deployment, gateways may need the
+// model omitted or renamed.
+func (c *Client) requestModel() string {
+	api := c.config.API
+	if api.OmitModel || api.Provider == ProviderAzure {
+		return ""
+	}
+	if model, ok := api.ModelMap[api.Model]; ok {
+		return model
+	}
+	return api.Model
+}
+
+// setAuth sets the authentication header of the provider
+func (c *Client) setAuth(h http.Header) {
+	if c.config.API.Provider == ProviderAzure {
+		h.Set("api-key", c.config.API.Key)
+		return
+	}
+	h.Set("Authorization", "Bearer "+c.config.API.Key)
+}
+
+// endpointURL returns the request URL: the endpoint, completed to the
+// deployment URL for Azure, with the configured query parameters
+func (c *Client) endpointURL() (string, error) {
+	api := c.config.API
+	if api.Provider != ProviderAzure && len(api.Query) == 0 {
+		return api.Endpoint, nil
+	}
+	u, err := url.Parse(api.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+	q := u.Query()
+
+	if api.Provider == ProviderAzure {
+		// A resource URL such as https://name.openai.azure.com is completed
+		// to the chat completions URL of the deployment
+		if !strings.Contains(u.Path, "/openai/deployments/") {
+			deployment := api.Deployment
+			if deployment == "" {
+				deployment = api.Model
+			}
+			u.Path = strings.TrimSuffix(u.Path, "/") + "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions"
+			u.RawPath = ""
+		}
+		if api.APIVersion != "" {
+			q.Set("api-version", api.APIVersion)
+		} else if !q.Has("api-version") {
+			q.Set("api-version", defaultAzureAPIVersion)
+		}
+	}
+
+	for k, v := range api.Query {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,182 @@
+package src
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token-bucket limiter enforcing both requests per minute
+// and tokens per minute. It is safe for concurrent use.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests bucket
+	tokens   bucket
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// bucket refills at rate units per second up to capacity; a zero capacity
+// means unlimited
+type bucket struct {
+	capacity  float64
+	available float64
+	rate      float64
+	last      time.Time
+}
+
+// NewRateLimiter creates a limiter for rpm requests and tpm tokens per minute;
+// zero disables the respective limit
+func NewRateLimiter(rpm, tpm int) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		requests: newBucket(rpm, now),
+		tokens:   newBucket(tpm, now),
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+func newBucket(perMinute int, now time.Time) bucket {
+	return bucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		rate:      float64(perMinute) / 60,
+		last:      now,
+	}
+}
+
+// refill adds the units accumulated since the last refill
+func (b *bucket) refill(now time.Time) {
+	if b.capacity == 0 {
+		return
+	}
+	b.available = min(b.capacity, b.available+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait returns how long until n units are available
+func (b *bucket) wait(n float64) time.Duration {
+	if b.capacity == 0 || b.available >= n {
+		return 0
+	}
+	return time.Duration((n - b.available) / b.rate * float64(time.Second))
+}
+
+// Wait blocks until one request using an estimated number of tokens may be
+// sent and reserves it. It returns the time spent waiting.
+func (l *RateLimiter) Wait(tokens int) time.Duration {
+	var waited time.Duration
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.requests.refill(now)
+		l.tokens.refill(now)
+
+		// A request larger than the bucket can never fit, so cap it
+		n := float64(tokens)
+		if l.tokens.capacity > 0 {
+			n = min(n, l.tokens.capacity)
+		}
+
+		d := max(l.requests.wait(1), l.tokens.wait(n))
+		if d == 0 {
+			if l.requests.capacity > 0 {
+				l.requests.available--
+			}
+			if l.tokens.capacity > 0 {
+				l.tokens.available -= n
+			}
+			l.mu.Unlock()
+			return waited
+		}
+		l.mu.Unlock()
+
+		l.sleep(d)
+		waited += d
+	}
+}
+
+// Reconcile corrects a reservation made with an estimated token count once
+// the actual usage reported by the provider is known
+func (l *RateLimiter) Reconcile(estimated, actual int) {
+	if actual <= 0 {
+		return
+	}
+	l.refund(estimated - actual)
+}
+
+// Release returns the tokens reserved for a request that failed without
+// using any, because it never reached the provider or was rejected
+func (l *RateLimiter) Release(estimated int) {
+	l.refund(estimated)
+}
+
+// refund adds tokens back to the bucket, up to its capacity
+func (l *RateLimiter) refund(tokens int) {
+	if l.tokens.capacity == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refill(l.now())
+	l.tokens.available = min(l.tokens.capacity, l.tokens.available+float64(tokens))
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*RateLimiter{}
+)
+
+// rateLimiterFor returns the limiter shared by all clients using the same
+// provider and model, or nil if no limit is configured for them
+func rateLimiterFor(cfg *Config, endpoint, model string) *RateLimiter {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	for _, rl := range cfg.RateLimits {
+		if rl.Provider != "" && !strings.Contains(host, rl.Provider) {
+			continue
+		}
+		if rl.Model != "" && rl.Model != model {
+			continue
+		}
+		if rl.RPM == 0 && rl.TPM == 0 {
+			return nil
+		}
+
+		key := host + "/" + model
+		limitersMu.Lock()
+		defer limitersMu.Unlock()
+		if l, ok := limiters[key]; ok {
+			return l
+		}
+		l := NewRateLimiter(rl.RPM, rl.TPM)
+		limiters[key] = l
+		return l
+	}
+	return nil
+}
+
+// estimateRequestTokens estimates the tokens a request counts against the
+// limit: the prompt plus the completion budget for each choice
+func estimateRequestTokens(request ChatRequest) int {
+	tokens := 0
+	for _, m := range request.Messages {
+		tokens += EstimateTokens(m.Content.Text) + 4 // per-message overhead
+		for _, p := range m.Content.Parts {
+			if p.ImageURL != nil {
+				tokens += imageTokens
+			}
+		}
+	}
+	completion := max(request.MaxTokens, request.MaxCompletionTokens)
+	if completion == 0 {
+		completion = 256
+	}
+	return tokens + completion*max(request.N, 1)
+}
@@ -0,0 +1,125 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeClock advances time when the limiter sleeps
+type fakeClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func newFakeLimiter(rpm, tpm int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := NewRateLimiter(rpm, tpm)
+	l.requests.last, l.tokens.last = clock.now, clock.now
+	l.now, l.sleep = clock.Now, clock.Sleep
+	return l, clock
+}
+
+func TestRateLimiterRequests(t *testing.T) {
+	l, clock := newFakeLimiter(2, 0)
+	l.Wait(100)
+	l.Wait(100)
+	if clock.slept != 0 {
+		t.Fatalf("burst within capacity waited %s", clock.slept)
+	}
+	// Two requests per minute refill one request every 30 seconds
+	if waited := l.Wait(100); waited != 30*time.Second {
+		t.Errorf("third request waited %s, want 30s", waited)
+	}
+}
+
+func TestRateLimiterTokensAndReconcile(t *testing.T) {
+	l, clock := newFakeLimiter(0, 600)
+	l.Wait(500)
+	// Only 100 of the reserved 500 tokens were used
+	l.Reconcile(500, 100)
+	if waited := l.Wait(400); waited != 0 {
+		t.Errorf("reconciled tokens not returned, waited %s", waited)
+	}
+	// 600 tokens per minute refill 10 tokens per second
+	if waited := l.Wait(200); waited != 10*time.Second {
+		t.Errorf("waited %s, want 10s", waited)
+	}
+	// Requests larger than the bucket are capped instead of blocking forever
+	clock.Sleep(time.Minute)
+	if waited := l.Wait(10000); waited != 0 {
+		t.Errorf("oversized request waited %s", waited)
+	}
+}
+
+func TestRateLimiterReleasedOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "overloaded"}`, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient(testConfig(srv.URL))
+	l, _ := newFakeLimiter(0, 600)
+	c.limiter = l
+	if _, err := c.Translate("hello", "zh"); err == nil {
+		t.Fatal("expected an API error")
+	}
+	if l.tokens.available != l.tokens.capacity {
+		t.Errorf("%.0f of %.0f tokens available after a failed request", l.tokens.available, l.tokens.capacity)
+	}
+
+	srv.Close()
+	if _, err := c.Translate("hello", "zh"); err == nil {
+		t.Fatal("expected a connection error")
+	}
+	if l.tokens.available != l.tokens.capacity {
+		t.Errorf("%.0f of %.0f tokens available after a failed connection", l.tokens.available, l.tokens.capacity)
+	}
+}
+
+func TestTruncatedResponseReleasedAndRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Promise more than is sent, so the connection drops mid-body
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`{"choices": [`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient(testConfig(srv.URL))
+	l, _ := newFakeLimiter(0, 600)
+	c.limiter = l
+	_, err := c.Translate("hello", "zh")
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("got %v, want a retryable read error", err)
+	}
+	if l.tokens.available != l.tokens.capacity {
+		t.Errorf("%.0f of %.0f tokens available after a truncated response", l.tokens.available, l.tokens.capacity)
+	}
+}
+
+func TestRateLimiterShared(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RateLimits = []RateLimitConfig{
+		{Provider: "example.com", Model: "small", RPM: 10},
+		{Provider: "example.com", TPM: 1000},
+	}
+	a := rateLimiterFor(cfg, "https://api.example.com/v1/chat/completions", "small")
+	b := rateLimiterFor(cfg, "https://api.example.com/v1/chat/completions", "small")
+	if a == nil || a != b {
+		t.Error("clients of the same provider and model should share a limiter")
+	}
+	if c := rateLimiterFor(cfg, "https://api.example.com/v1/chat/completions", "large"); c == nil || c == a || c.tokens.capacity != 1000 {
+		t.Error("other models should use the next matching limit")
+	}
+	if rateLimiterFor(cfg, "https://api.openai.com/v1/chat/completions", "small") != nil {
+		t.Error("unmatched provider should not be limited")
+	}
+}
@@ -0,0 +1,80 @@
+package src
+
+import (
+	"fmt"
+	"strings"
+)
+
+// romanizationSystems are the romanizations used for each target language
+var romanizationSystems = map[string]string{
+	"zh": "Hanyu Pinyin with tone marks (for example: nǐ hǎo), syllables of a word written together and words separated by spaces",
+	"ja": "modified Hepburn romaji with macrons for long vowels (for example: Tōkyō e ikimasu)",
+	"ko": "the Revised Romanization of Korean (for example: annyeonghaseyo)",
+}
+
+// romanizePromptTemplate asks for the romanization of a text
+const romanizePromptTemplate = `Romanize the following %s text using %s.
+Romanize the text exactly as written, keep punctuation, numbers and Latin-script words unchanged, and do not translate it.
+Reply with JSON only, in the form: {"romanization": "..."}
+
+Text: %s`
+
+// romanizationSystem returns the romanization of lang, or "" if the
+// language is written in Latin script or not supported
+func romanizationSystem(lang string) string {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	return romanizationSystems[base]
+}
+
+// Romanize returns the romanization of text written in language
+func (c *Client) Romanize(text, language string) (string, error) {
+	system := romanizationSystem(language)
+	if system == "" {
+		return "", fmt.Errorf("no romanization for %s", getLanguageName(language))
+	}
+	prompt := fmt.Sprintf(romanizePromptTemplate, getLanguageName(language), system, text)
+	resp, err := c.Chat([]Message{{Role: "user", Content: TextContent(prompt)}})
+	if err != nil {
+		return "", err
+	}
+
+	var answer struct {
+		Romanization string `json:"romanization"`
+	}
+	if err := parseJSONAnswer(resp.Choices[0].Message.Content.Text, &answer); err != nil {
+		return "", err
+	}
+	if answer.Romanization == "" {
+		return "", fmt.Errorf("empty romanization")
+	}
+	return strings.TrimSpace(answer.Romanization), nil
+}
+
+// romanize returns the romanization of a translation, using the cache
+func (t *Translator) romanize(text, lang string) (string, error) {
+	key := CacheKey("romanize", t.config.API.Model, lang, text)
+	if romanization, ok := t.cache.Get(key); ok {
+		return romanization, nil
+	}
+
+	var romanization string
+	_, err := t.failover(func(c *Client) error {
+		t.logger.Debug("calling API", "lang", lang, "romanize", true, "backend", c.Name())
+		var err error
+		romanization, err = c.Romanize(text, lang)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	t.cache.Set(key, romanization)
+	return romanization, nil
+}
+
+// formatRomanization renders the romanization below a translation
+func formatRomanization(translation *Translation, color bool) string {
+	if translation.Romanization == "" {
+		return ""
+	}
+	return "\n  " + c(translation.Romanization, colorDim, color)
+}
@@ -0,0 +1,64 @@
+package src
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTranslateRomanize(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		switch {
+		case strings.HasPrefix(prompt, "Romanize the following Chinese text using Hanyu Pinyin"):
+			return `{"romanization": "nǐ hǎo, shìjiè"}`
+		case strings.Contains(prompt, "Chinese"):
+			return "你好，世界"
+		default:
+			return "Bonjour le monde"
+		}
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Advanced.Romanize = true
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.TranslateTo("Hello world", []string{"zh", "fr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Translations[0].Romanization; got != "nǐ hǎo, shìjiè" {
+		t.Errorf("got romanization %q", got)
+	}
+	if got := result.Translations[1].Romanization; got != "" {
+		t.Errorf("French has romanization %q", got)
+	}
+	if len(*requests) != 3 {
+		t.Errorf("got %d requests, want 3", len(*requests))
+	}
+
+	want := "Original: Hello world\n------\n• Chinese: 你好，世界\n  nǐ hǎo, shìjiè\n• French: Bonjour le monde"
+	if got := result.Format(false); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	data, _ := json.Marshal(result.Translations[0])
+	if !strings.Contains(string(data), `"romanization":"nǐ hǎo, shìjiè"`) {
+		t.Errorf("romanization missing from JSON: %s", data)
+	}
+
+	// The romanization is cached with the translation
+	if _, err := trans.TranslateTo("Hello world", []string{"zh"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 3 {
+		t.Errorf("got %d requests after a cached translation, want 3", len(*requests))
+	}
+}
+
+func TestRomanizationSystem(t *testing.T) {
+	for lang, want := range map[string]string{"zh": "Pinyin", "zh-TW": "Pinyin", "ja": "Hepburn", "ko": "Revised Romanization", "fr": ""} {
+		got := romanizationSystem(lang)
+		if want == "" && got != "" || !strings.Contains(got, want) {
+			t.Errorf("romanizationSystem(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package src
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Scrub kinds, in the order they are detected
+const (
+	ScrubSecret = "secret"
+	ScrubEmail  = "email"
+	ScrubCard   = "card"
+	ScrubIP     = "ip"
+	ScrubPhone  = "phone"
+)
+
+// scrubKinds lists the detectors in detection order; earlier detectors win
+// when matches overlap
+var scrubKinds = []string{ScrubSecret, ScrubEmail, ScrubCard, ScrubIP, ScrubPhone}
+
+var (
+	secretRe = regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{16,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})\b|\b[A-Za-z0-9_-]{32,}\b`)
+	emailRe  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	cardRe   = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	ipRe     = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|(?:[0-9A-Fa-f]{1,4})?(?::[0-9A-Fa-f]{0,4}){2,7}`)
+	phoneRe  = regexp.MustCompile(`(?:\+|\b)\d[\d ().-]{6,}\d\b|\(\d{2,4}\)[\d ().-]{6,}\d\b`)
+	dateRe   = regexp.MustCompile(`^\d{4}[-.]\d{1,2}[-.]\d{1,2}$`)
+
+	// placeholderRestoreRe also matches placeholders whose brackets or
+	// spacing the model changed
+	placeholderRestoreRe = regexp.MustCompile(`[\[［【]\s*(SECRET|EMAIL|CARD|IP|PHONE)_(\d+)\s*[\]］】]`)
+)
+
+// Redaction is a value replaced by a placeholder
+type Redaction struct {
+	Placeholder string `json:"placeholder"`
+	Kind        string `json:"kind"`
+	Value       string `json:"value"`
+}
+
+// Scrubber replaces personal data and secrets with placeholders such as
+// [EMAIL_1] and restores them in the translation. A nil Scrubber leaves text
+// unchanged. The same value always gets the same placeholder, so one
+// Scrubber can be used for all chunks of a document.
+type Scrubber struct {
+	kinds      []string
+	redactions []Redaction
+	byValue    map[string]string
+	byKey      map[string]string
+	counts     map[string]int
+}
+
+// NewScrubber creates a scrubber for the given kinds; no kinds means all
+func NewScrubber(kinds []string) *Scrubber {
+	if len(kinds) == 0 {
+		kinds = scrubKinds
+	}
+	return &Scrubber{
+		kinds:   kinds,
+		byValue: map[string]string{},
+		byKey:   map[string]string{},
+		counts:  map[string]int{},
+	}
+}
+
+// validateScrubKinds checks that kinds only names known detectors
+func validateScrubKinds(kinds []string) error {
+	for _, kind := range kinds {
+		known := false
+		for _, k := range scrubKinds {
+			known = known || k == kind
+		}
+		if !known {
+			return fmt.Errorf("unknown scrub kind %q (available: %s)", kind, strings.Join(scrubKinds, ", "))
+		}
+	}
+	return nil
+}
+
+// enabled reports whether the scrubber detects kind
+func (s *Scrubber) enabled(kind string) bool {
+	for _, k := range s.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Scrub replaces the sensitive values in text with placeholders
+func (s *Scrubber) Scrub(text string) string {
+	if s == nil {
+		return text
+	}
+	for _, kind := range scrubKinds {
+		if !s.enabled(kind) {
+			continue
+		}
+		re, valid := scrubDetector(kind)
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			if !valid(match) {
+				return match
+			}
+			return s.placeholder(kind, match)
+		})
+	}
+	return text
+}
+
+// Restore replaces the placeholders in text with the original values
+func (s *Scrubber) Restore(text string) string {
+	if s == nil {
+		return text
+	}
+	return placeholderRestoreRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := placeholderRestoreRe.FindStringSubmatch(match)
+		if value, ok := s.byKey[m[1]+"_"+m[2]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// scrubMessages returns a copy of messages with the sensitive values in
+// their text replaced by placeholders
+func (s *Scrubber) scrubMessages(messages []Message) []Message {
+	if s == nil {
+		return messages
+	}
+	scrubbed := make([]Message, len(messages))
+	for i, m := range messages {
+		if m.Content.Parts == nil {
+			m.Content = TextContent(s.Scrub(m.Content.Text))
+		} else {
+			parts := make([]ContentPart, len(m.Content.Parts))
+			for j, part := range m.Content.Parts {
+				part.Text = s.Scrub(part.Text)
+				parts[j] = part
+			}
+			m.Content = PartsContent(parts...)
+		}
+		scrubbed[i] = m
+	}
+	return scrubbed
+}
+
+// Redactions returns the values replaced so far
+func (s *Scrubber) Redactions() []Redaction {
+	if s == nil {
+		return nil
+	}
+	return s.redactions
+}
+
+// placeholder returns the placeholder of value, creating one if needed
+func (s *Scrubber) placeholder(kind, value string) string {
+	if p, ok := s.byValue[value]; ok {
+		return p
+	}
+	s.counts[kind]++
+	key := fmt.Sprintf("%s_%d", strings.ToUpper(kind), s.counts[kind])
+	p := "[" + key + "]"
+	s.byValue[value] = p
+	s.byKey[key] = value
+	s.redactions = append(s.redactions, Redaction{Placeholder: p, Kind: kind, Value: value})
+	return p
+}
+
+// scrubDetector returns the pattern of a kind and a check for its matches
+func scrubDetector(kind string) (*regexp.Regexp, func(string) bool) {
+	switch kind {
+	case ScrubSecret:
+		return secretRe, isSecret
+	case ScrubEmail:
+		return emailRe, func(string) bool { return true }
+	case ScrubCard:
+		return cardRe, func(m string) bool { return luhnValid(digits(m)) }
+	case ScrubIP:
+		return ipRe, isIP
+	default:
+		return phoneRe, isPhone
+	}
+}
+
+// isSecret accepts known key formats and long tokens mixing letters and digits
+func isSecret(m string) bool {
+	if len(m) < 32 || strings.HasPrefix(m, "sk-") || strings.HasPrefix(m, "AKIA") {
+		return true
+	}
+	var letter, digit bool
+	for _, r := range m {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+	return letter && digit
+}
+
+// isIP accepts IPv4 addresses and IPv6 addresses with at least two groups,
+// so that scope operators such as std:: are left alone
+func isIP(m string) bool {
+	if net.ParseIP(m) == nil {
+		return false
+	}
+	if !strings.Contains(m, ":") {
+		return true
+	}
+	groups := 0
+	for _, g := range strings.Split(m, ":") {
+		if g != "" {
+			groups++
+		}
+	}
+	return groups >= 2 && strings.ContainsAny(m, "0123456789")
+}
+
+// isPhone accepts numbers written like phone numbers: with a leading +, an
+// area code in parentheses or separators between digit groups, excluding dates
+func isPhone(m string) bool {
+	n := len(digits(m))
+	if n < 8 || n > 15 || dateRe.MatchString(m) {
+		return false
+	}
+	return strings.HasPrefix(m, "+") || strings.ContainsAny(m, " ()-.")
+}
+
+// digits returns the ASCII digits of s
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid reports whether a card number passes the Luhn check
+func luhnValid(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range number {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
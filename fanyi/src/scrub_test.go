@@ -0,0 +1,158 @@
+package src
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestScrubber(t *testing.T) {
+	s := NewScrubber(nil)
+	input := "user alice@example.com from 10.0.0.12 and fe80::1 paid with 4111 1111 1111 1111 " +
+		"(not 4111 1111 1111 1112), key sk-abcdefghijklmnopqrstuvwx, call +1 415-555-0100 " +
+		"on 2024-01-15 at 12:30:45, see std::vector, mail alice@example.com again"
+	want := "user [EMAIL_1] from [IP_1] and [IP_2] paid with [CARD_1] " +
+		"(not 4111 1111 1111 1112), key [SECRET_1], call [PHONE_1] " +
+		"on 2024-01-15 at 12:30:45, see std::vector, mail [EMAIL_1] again"
+	if got := s.Scrub(input); got != want {
+		t.Errorf("Scrub:\ngot  %q\nwant %q", got, want)
+	}
+	if n := len(s.Redactions()); n != 6 {
+		t.Errorf("got %d redactions, want 6", n)
+	}
+
+	// The model may change brackets and spacing
+	got := s.Restore("用户【EMAIL_1】来自 [ IP_1 ]，卡号 [CARD_1]，未知 [EMAIL_9]")
+	if want := "用户alice@example.com来自 10.0.0.12，卡号 4111 1111 1111 1111，未知 [EMAIL_9]"; got != want {
+		t.Errorf("Restore:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestScrubberKinds(t *testing.T) {
+	s := NewScrubber([]string{ScrubEmail})
+	if got := s.Scrub("alice@example.com 10.0.0.1"); got != "[EMAIL_1] 10.0.0.1" {
+		t.Errorf("got %q", got)
+	}
+	if err := validateScrubKinds([]string{"ssn"}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	for number, want := range map[string]bool{
+		"4111111111111111":     true,
+		"5500005555555559":     true,
+		"378282246310005":      true,
+		"4111111111111112":     false,
+		"123456789012":         false,
+		"6011000990139424":     true,
+		"60110009901394240000": false,
+	} {
+		if got := luhnValid(number); got != want {
+			t.Errorf("luhnValid(%q) = %v, want %v", number, got, want)
+		}
+	}
+}
+
+func TestClientTranslateScrubs(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "请联系 [EMAIL_1]" })
+	cfg := testConfig(srv.URL)
+	cfg.Scrub.Enabled = true
+
+	got, err := NewClient(cfg).Translate("Contact bob@example.org", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "请联系 bob@example.org" {
+		t.Errorf("got %q", got)
+	}
+	if prompt := (*requests)[0].Messages[0].Content.Text; strings.Contains(prompt, "bob@example.org") {
+		t.Errorf("email was sent to the API: %q", prompt)
+	}
+}
+
+func TestConversationScrubsWithOneScrubber(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		return prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):]
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Scrub.Enabled = true
+	cfg.Advanced.SystemPrompt = "Questions go to ops@example.com."
+	cfg.ContextWindow.Summary = false
+	conv := NewClient(cfg).NewConversation("zh")
+
+	for _, text := range []string{"Mail bob@example.org", "Again bob@example.org"} {
+		got, err := conv.Translate(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != text {
+			t.Errorf("got %q, want %q", got, text)
+		}
+	}
+	for _, req := range *requests {
+		body, _ := json.Marshal(req)
+		if strings.Contains(string(body), "@example") {
+			t.Errorf("email was sent to the API: %s", body)
+		}
+	}
+	if last := (*requests)[1].Messages; !strings.HasSuffix(last[len(last)-1].Content.Text, "Again [EMAIL_2]") {
+		t.Errorf("placeholder changed between chunks: %q", last[len(last)-1].Content.Text)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "" })
+	cfg := testConfig(srv.URL)
+	cfg.Scrub.Enabled = true
+	trans, _ := NewTranslator(cfg)
+
+	d, err := trans.DryRun("ping 192.168.1.1", "ja")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("dry run sent %d requests", len(*requests))
+	}
+	if len(d.Requests) != 1 || len(d.Requests[0].Redactions) != 1 {
+		t.Fatalf("unexpected dry run: %+v", d)
+	}
+	if body := d.Format(false); !strings.Contains(body, "ping [IP_1]") {
+		t.Errorf("unexpected output:\n%s", body)
+	}
+}
+
+func TestScrubCoversEveryMode(t *testing.T) {
+	const secret = "bob@example.org"
+	srv, requests := newTestServer(t, func(ChatRequest) string { return "[EMAIL_1]" })
+	cfg := testConfig(srv.URL)
+	cfg.Scrub.Enabled = true
+	cfg.Verify.Enabled = true
+	cfg.Advanced.Romanize = true
+	cfg.Cache.Enabled = false
+	trans, _ := NewTranslator(cfg)
+
+	// Errors from parsing the placeholder replies do not matter here, only
+	// what was sent
+	for mode, run := range map[string]func() error{
+		"verify and romanize": func() error { _, err := trans.Translate("Mail "+secret, "zh"); return err },
+		"alternatives":        func() error { _, err := trans.Alternatives("Mail "+secret, "zh", 2); return err },
+		"explain":             func() error { _, err := trans.Explain("Mail "+secret, "zh"); return err },
+		"dictionary":          func() error { _, err := trans.Lookup(secret, "zh"); return err },
+		"html":                func() error { _, _, err := trans.TranslateHTML("<p>Mail <a>"+secret+"</a></p>", "zh"); return err },
+		"xml":                 func() error { _, _, err := trans.TranslateXML("<note>Mail "+secret+"</note>", "zh"); return err },
+	} {
+		*requests = nil
+		run()
+		if len(*requests) == 0 {
+			t.Errorf("%s: no request sent", mode)
+		}
+		for _, req := range *requests {
+			body, _ := json.Marshal(req)
+			if strings.Contains(string(body), secret) {
+				t.Errorf("%s: email was sent to the API: %s", mode, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package src
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// sentenceClosers may follow a sentence terminator and belong to the sentence
+const sentenceClosers = `"'”’」』）)]】》`
+
+// abbreviations end with a period that does not end the sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"vs": true, "etc": true, "e.g": true, "i.e": true, "cf": true, "no": true,
+	"fig": true, "approx": true,
+}
+
+var blankLinesRe = regexp.MustCompile(`\n[ \t\r]*\n\s*`)
+
+// span is a piece of a document together with the whitespace preceding it
+type span struct {
+	sep  string
+	text string
+}
+
+// segmentDocument splits text into sentences, each with the whitespace
+// before it, so that concatenating sep and text of all spans restores text.
+// Trailing whitespace ends up in a last span without text.
+func segmentDocument(text string) []span {
+	var spans []span
+	carry := ""
+	pos := 0
+	for _, loc := range append(blankLinesRe.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		for _, sentence := range splitSentences(text[pos:loc[0]]) {
+			core := strings.TrimLeftFunc(sentence, unicode.IsSpace)
+			carry += sentence[:len(sentence)-len(core)]
+			trimmed := strings.TrimRightFunc(core, unicode.IsSpace)
+			if trimmed != "" {
+				spans = append(spans, span{sep: carry, text: trimmed})
+				carry = ""
+			}
+			carry += core[len(trimmed):]
+		}
+		// The blank lines become part of the separator of the next paragraph
+		carry += text[loc[0]:loc[1]]
+		pos = loc[1]
+	}
+	if carry != "" {
+		spans = append(spans, span{sep: carry})
+	}
+	return spans
+}
+
+// splitSentences splits a paragraph after Latin sentence terminators followed
+// by whitespace and after CJK terminators. The pieces keep their trailing
+// whitespace, so they concatenate to the paragraph.
+func splitSentences(paragraph string) []string {
+	runes := []rune(paragraph)
+	var sentences []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		cjk := strings.ContainsRune("。！？；…", r)
+		if !cjk && !strings.ContainsRune(".!?", r) {
+			continue
+		}
+
+		// Keep repeated terminators and closing quotes with the sentence
+		end := i + 1
+		for end < len(runes) && (strings.ContainsRune(sentenceClosers+".!?。！？…", runes[end])) {
+			end++
+		}
+		if !cjk {
+			if end < len(runes) && !unicode.IsSpace(runes[end]) {
+				continue
+			}
+			if r == '.' && isAbbreviation(runes[start:i]) {
+				continue
+			}
+		}
+		for end < len(runes) && unicode.IsSpace(runes[end]) {
+			end++
+		}
+		if end < len(runes) {
+			sentences = append(sentences, string(runes[start:end]))
+			start = end
+		}
+		i = end - 1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+// isAbbreviation reports whether the sentence so far ends with an
+// abbreviation or an initial
+func isAbbreviation(sentence []rune) bool {
+	word := string(sentence)
+	if i := strings.LastIndexFunc(word, unicode.IsSpace); i >= 0 {
+		word = word[i+1:]
+	}
+	word = strings.TrimLeft(word, sentenceClosers+"(")
+	if len([]rune(word)) == 1 && unicode.IsUpper([]rune(word)[0]) {
+		return true
+	}
+	return abbreviations[strings.ToLower(word)]
+}
+
+// packSpans groups consecutive spans into chunks of at most budget estimated
+// tokens. Sentences longer than the budget are cut into pieces.
+func packSpans(spans []span, budget int) []span {
+	var chunks []span
+	var cur strings.Builder
+	var curSep string
+	tokens := 0
+	flush := func() {
+		if cur.Len() > 0 {
+			chunks = append(chunks, span{sep: curSep, text: cur.String()})
+			cur.Reset()
+			tokens = 0
+		}
+	}
+
+	for _, s := range spans {
+		if s.text == "" {
+			flush()
+			chunks = append(chunks, s)
+			continue
+		}
+		n := EstimateTokens(s.sep + s.text)
+		if cur.Len() > 0 && tokens+n > budget {
+			flush()
+		}
+		if cur.Len() == 0 {
+			curSep = s.sep
+			// Cut sentences that do not fit into any chunk
+			for rest := s.text; rest != ""; {
+				piece := truncateTokens(rest, budget)
+				if piece == "" {
+					piece = string([]rune(rest)[:1])
+				}
+				if len(piece) < len(rest) {
+					// Prefer cutting between words
+					if i := strings.LastIndexFunc(piece, unicode.IsSpace); i > 0 {
+						piece = piece[:i]
+					}
+				}
+				if piece == rest {
+					cur.WriteString(piece)
+					tokens = EstimateTokens(piece)
+					break
+				}
+				chunks = append(chunks, span{sep: curSep, text: strings.TrimRightFunc(piece, unicode.IsSpace)})
+				trimmed := strings.TrimLeftFunc(rest[len(piece):], unicode.IsSpace)
+				curSep = rest[len(piece) : len(rest)-len(trimmed)]
+				rest = trimmed
+			}
+			continue
+		}
+		cur.WriteString(s.sep + s.text)
+		tokens += n
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,176 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello world. How are you? Fine!", []string{"Hello world. ", "How are you? ", "Fine!"}},
+		{`He said "stop." Then he left.`, []string{`He said "stop." `, "Then he left."}},
+		{"Dr. Smith met J. Doe, e.g. at 3.14 pm. Done.", []string{"Dr. Smith met J. Doe, e.g. at 3.14 pm. ", "Done."}},
+		{"你好。今天天气很好！是吗？", []string{"你好。", "今天天气很好！", "是吗？"}},
+		{"他说：“走吧。”然后走了。", []string{"他说：“走吧。”", "然后走了。"}},
+		{"Wait... what?! Yes.", []string{"Wait... ", "what?! ", "Yes."}},
+	}
+	for _, tt := range tests {
+		if got := splitSentences(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitSentences(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentDocumentRestoresText(t *testing.T) {
+	text := "\n  Title\n\nFirst sentence. Second sentence.\r\n\r\n第一句。第二句。\n\n\n"
+	spans := segmentDocument(text)
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.sep + s.text)
+	}
+	if b.String() != text {
+		t.Errorf("got %q, want %q", b.String(), text)
+	}
+	if len(spans) != 6 {
+		t.Errorf("got %d spans, want 6: %q", len(spans), spans)
+	}
+}
+
+func TestPackSpans(t *testing.T) {
+	spans := segmentDocument("One two three four. Five six seven eight. Nine ten.\n\n" + strings.Repeat("长", 25) +
+		"\n\nthe quick brown fox jumps over the lazy dog again and again")
+	chunks := packSpans(spans, 10)
+	for _, chunk := range chunks {
+		if n := EstimateTokens(chunk.text); n > 10 {
+			t.Errorf("chunk %q has %d tokens, budget 10", chunk.text, n)
+		}
+	}
+	want := []span{
+		{text: "One two three four. Five six seven eight."},
+		{sep: " ", text: "Nine ten."},
+		{sep: "\n\n", text: strings.Repeat("长", 10)},
+		{text: strings.Repeat("长", 10)},
+		{text: strings.Repeat("长", 5)},
+		{sep: "\n\n", text: "the quick brown fox jumps over the lazy dog again"},
+		{sep: " ", text: "and again"},
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("got %q, want %q", chunks, want)
+	}
+}
+
+func TestChunkBudget(t *testing.T) {
+	for _, tt := range []struct {
+		budget, maxTokens, want int
+	}{
+		{0, 1000, 500},
+		{0, 3, 1},
+		{0, 0, defaultChunkBudget},
+		{200, 0, 200},
+	} {
+		cfg := DefaultConfig()
+		cfg.Chunking.TokenBudget, cfg.API.MaxTokens = tt.budget, tt.maxTokens
+		trans := &Translator{config: cfg}
+		if got := trans.chunkBudget(); got != tt.want {
+			t.Errorf("budget %d, max tokens %d: got %d, want %d", tt.budget, tt.maxTokens, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateChunked(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		return strings.ToUpper(prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):])
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Cache.Enabled = false
+	cfg.Chunking.TokenBudget = 5
+	trans, _ := NewTranslator(cfg)
+
+	text := "Alpha beta gamma. Delta epsilon zeta. Eta theta.\n\nIota kappa lambda. Mu nu xi.\n"
+	var progress []int
+	got, _, err := trans.TranslateChunked(text, "fr", ChunkOptions{Progress: func(done, total int) {
+		progress = append(progress, done)
+		if total != 5 {
+			t.Errorf("got total %d, want 5", total)
+		}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strings.ToUpper(text) {
+		t.Errorf("got %q, want %q", got, strings.ToUpper(text))
+	}
+	if len(*requests) != 5 {
+		t.Errorf("got %d requests, want 5", len(*requests))
+	}
+	if !reflect.DeepEqual(progress, []int{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("got progress %v", progress)
+	}
+}
+
+func TestTranslateChunkedResumesFromCheckpoint(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		fail     = true
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		text := prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):]
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if fail && strings.HasPrefix(text, "Third") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{Choices: []Choice{{Message: Message{Content: TextContent(strings.ToUpper(text))}}}})
+	}))
+	defer srv.Close()
+
+	cfg := testConfig(srv.URL)
+	cfg.Cache.Enabled = false
+	cfg.Chunking.TokenBudget = 4
+	cfg.Chunking.Concurrency = 1
+	trans, _ := NewTranslator(cfg)
+	checkpoint := filepath.Join(t.TempDir(), "doc.ckpt")
+	text := "First one here. Second one here. Third one here."
+
+	if _, _, err := trans.TranslateChunked(text, "fr", ChunkOptions{Checkpoint: checkpoint}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatalf("checkpoint not written: %v", err)
+	}
+
+	mu.Lock()
+	fail, requests = false, 0
+	mu.Unlock()
+	got, _, err := trans.TranslateChunked(text, "fr", ChunkOptions{Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strings.ToUpper(text) {
+		t.Errorf("got %q, want %q", got, strings.ToUpper(text))
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests after resuming, want 1", requests)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint not removed: %v", err)
+	}
+}
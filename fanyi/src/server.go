@@ -0,0 +1,305 @@
+package src
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// Server exposes a Translator over HTTP with a native REST API and a
+// LibreTranslate-compatible API
+type Server struct {
+	translator *Translator
+	config     *Config
+	sem        chan struct{}
+}
+
+// NewServer creates an HTTP server for the translator
+func NewServer(t *Translator) *Server {
+	return &Server{
+		translator: t,
+		config:     t.config,
+		sem:        make(chan struct{}, t.config.Server.MaxConcurrent),
+	}
+}
+
+// TranslateRequest is the body of a native POST /translate request
+type TranslateRequest struct {
+	Text    string   `json:"text"`
+	Targets []string `json:"targets"`
+}
+
+// libreRequest is the body of a LibreTranslate POST /translate request
+type libreRequest struct {
+	Q      any    `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+}
+
+// libreLanguage is an entry of the LibreTranslate GET /languages response
+type libreLanguage struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Targets []string `json:"targets"`
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /translate", s.handleTranslate)
+	mux.HandleFunc("GET /languages", s.handleLanguages)
+	return mux
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, then shuts
+// down gracefully, waiting for in-flight requests up to the shutdown timeout
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		s.translator.logger.Info("listening", "addr", addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.translator.logger.Info("shutting down")
+	timeout := time.Duration(s.config.Server.ShutdownTimeout) * time.Second
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown failed: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handleTranslate serves both the native and the LibreTranslate request
+// formats; LibreTranslate requests are recognised by form encoding or a q field
+func (s *Server) handleTranslate(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.config.Server.MaxBodyBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(s.config.Server.MaxBodyBytes); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			writeError(w, requestErrorStatus(err), err)
+			return
+		}
+		var texts []string
+		if r.Form.Has("q") {
+			texts = r.Form["q"]
+		}
+		s.serveLibre(w, r, texts, len(texts) > 1, r.FormValue("target"), r.FormValue("format"))
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, requestErrorStatus(err), fmt.Errorf("invalid JSON body: %w", err))
+		return
+	}
+
+	if _, ok := body["q"]; ok {
+		var req libreRequest
+		if err := remarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		texts, batch, err := libreTexts(req.Q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.serveLibre(w, r, texts, batch, req.Target, req.Format)
+		return
+	}
+
+	var req TranslateRequest
+	if err := remarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.checkText(req.Text); err != nil {
+		writeError(w, requestErrorStatus(err), err)
+		return
+	}
+	if len(req.Targets) == 0 {
+		req.Targets = s.config.Languages.Priority
+	}
+	if len(req.Targets) > s.config.Server.MaxTargets {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("at most %d targets allowed", s.config.Server.MaxTargets))
+		return
+	}
+
+	if !s.acquire(r.Context()) {
+		writeError(w, http.StatusServiceUnavailable, r.Context().Err())
+		return
+	}
+	defer s.release()
+
+	result, err := s.translator.TranslateTo(req.Text, req.Targets)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveLibre answers a LibreTranslate translate request; format is "text"
+// (the default) or "html"
+func (s *Server) serveLibre(w http.ResponseWriter, r *http.Request, texts []string, batch bool, target, format string) {
+	if len(texts) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: missing q parameter"))
+		return
+	}
+	if target == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: missing target parameter"))
+		return
+	}
+	if format != "" && format != "text" && format != "html" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: unsupported format %q", format))
+		return
+	}
+	for _, text := range texts {
+		if err := s.checkText(text); err != nil {
+			writeError(w, requestErrorStatus(err), err)
+			return
+		}
+	}
+
+	if !s.acquire(r.Context()) {
+		writeError(w, http.StatusServiceUnavailable, r.Context().Err())
+		return
+	}
+	defer s.release()
+
+	var err error
+	translations := make([]string, 0, len(texts))
+	for _, text := range texts {
+		var translation string
+		if format == "html" {
+			translation, _, err = s.translator.TranslateHTML(text, target)
+		} else {
+			var t *Translation
+			if t, err = s.translator.translateToLanguage(text, target); err == nil {
+				translation = t.Text
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		translations = append(translations, translation)
+	}
+
+	if batch {
+		writeJSON(w, http.StatusOK, map[string]any{"translatedText": translations})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"translatedText": translations[0]})
+}
+
+// handleLanguages lists the configured languages in LibreTranslate format
+func (s *Server) handleLanguages(w http.ResponseWriter, r *http.Request) {
+	codes := s.config.Languages.Common
+	languages := make([]libreLanguage, 0, len(codes))
+	for _, code := range codes {
+		languages = append(languages, libreLanguage{Code: code, Name: getLanguageName(code), Targets: codes})
+	}
+	writeJSON(w, http.StatusOK, languages)
+}
+
+// errTextTooLong is returned by checkText for text over the length limit
+var errTextTooLong = errors.New("text too long")
+
+// checkText enforces the request text limits
+func (s *Server) checkText(text string) error {
+	if text == "" {
+		return fmt.Errorf("text is required")
+	}
+	if n := utf8.RuneCountInString(text); n > s.config.Server.MaxTextLength {
+		return fmt.Errorf("%w: %d characters, limit is %d", errTextTooLong, n, s.config.Server.MaxTextLength)
+	}
+	return nil
+}
+
+// acquire waits for a free translation slot or for the request to be cancelled
+func (s *Server) acquire(ctx context.Context) bool {
+	select {
+	case s.sem <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Server) release() {
+	<-s.sem
+}
+
+// libreTexts accepts q as a single string or an array of strings
+func libreTexts(q any) ([]string, bool, error) {
+	switch v := q.(type) {
+	case string:
+		return []string{v}, false, nil
+	case []any:
+		texts := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("invalid request: q must contain strings")
+			}
+			texts = append(texts, text)
+		}
+		return texts, true, nil
+	}
+	return nil, false, fmt.Errorf("invalid request: q must be a string or an array of strings")
+}
+
+// remarshal decodes an already parsed JSON object into v
+func remarshal(body map[string]json.RawMessage, v any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	return nil
+}
+
+// requestErrorStatus maps body read and text limit errors to a status code
+func requestErrorStatus(err error) int {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) || errors.Is(err, errTextTooLong) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
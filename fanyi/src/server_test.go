@@ -0,0 +1,133 @@
+package src
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestAPI(t *testing.T) (*httptest.Server, *[]ChatRequest) {
+	t.Helper()
+	upstream, requests := newTestServer(t, func(req ChatRequest) string {
+		if strings.Contains(req.Messages[len(req.Messages)-1].Content.Text, "Chinese") {
+			return "你好"
+		}
+		return "hello"
+	})
+	trans, _ := NewTranslator(testConfig(upstream.URL))
+	api := httptest.NewServer(NewServer(trans).Handler())
+	t.Cleanup(api.Close)
+	return api, requests
+}
+
+func TestServerNativeTranslate(t *testing.T) {
+	api, requests := newTestAPI(t)
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Post(api.URL+"/translate", "application/json",
+			strings.NewReader(`{"text": "hello", "targets": ["zh"]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result Result
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || len(result.Translations) != 1 || result.Translations[0].Text != "你好" {
+			t.Fatalf("unexpected response %d: %+v", resp.StatusCode, result)
+		}
+	}
+	if len(*requests) != 1 {
+		t.Errorf("second request was not served from cache: %d upstream calls", len(*requests))
+	}
+}
+
+func TestServerLibreTranslate(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	resp, err := http.Post(api.URL+"/translate", "application/json",
+		strings.NewReader(`{"q": ["hello", "hi"], "source": "auto", "target": "zh"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batch struct {
+		TranslatedText []string `json:"translatedText"`
+	}
+	json.NewDecoder(resp.Body).Decode(&batch)
+	resp.Body.Close()
+	if len(batch.TranslatedText) != 2 || batch.TranslatedText[1] != "你好" {
+		t.Errorf("unexpected batch response: %+v", batch)
+	}
+
+	resp, err = http.PostForm(api.URL+"/translate", url.Values{"q": {"hello"}, "target": {"zh"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var single struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	json.NewDecoder(resp.Body).Decode(&single)
+	resp.Body.Close()
+	if single.TranslatedText != "你好" {
+		t.Errorf("unexpected form response: %+v", single)
+	}
+
+	resp, err = http.Get(api.URL + "/languages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var languages []libreLanguage
+	json.NewDecoder(resp.Body).Decode(&languages)
+	resp.Body.Close()
+	if len(languages) == 0 || languages[0].Code != "zh" || languages[0].Name != "Chinese" {
+		t.Errorf("unexpected languages: %+v", languages)
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	long := strings.Repeat("a", DefaultConfig().Server.MaxTextLength+1)
+	resp, err := http.Post(api.URL+"/translate", "application/json",
+		strings.NewReader(`{"text": "`+long+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+
+	for _, body := range []string{`{"q": "hi"}`, `{"text": ""}`, `{"q": "", "target": "zh"}`} {
+		resp, err = http.Post(api.URL+"/translate", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", body, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServerLibreTranslateHTML(t *testing.T) {
+	trans, _ := newUpperServer(t)
+	api := httptest.NewServer(NewServer(trans).Handler())
+	t.Cleanup(api.Close)
+
+	resp, err := http.Post(api.URL+"/translate", "application/json",
+		strings.NewReader(`{"q": "<p>Hello <b>world</b></p>", "target": "zh", "format": "html"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var single struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	json.NewDecoder(resp.Body).Decode(&single)
+	resp.Body.Close()
+	if want := "<p>HELLO <b>WORLD</b></p>"; single.TranslatedText != want {
+		t.Errorf("got %q, want %q", single.TranslatedText, want)
+	}
+}
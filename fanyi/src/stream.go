@@ -0,0 +1,77 @@
+package src
+
+import (
+	"fmt"
+	"io"
+)
+
+// TranslateStream translates the paragraphs of sc into targetLang, writing
+// each translation to w as soon as it is done. The whitespace between
+// paragraphs and the line endings of the input are preserved. If targetLang
+// is empty it is chosen from the priority languages based on the first
+// paragraph; the language used is returned.
+func (t *Translator) TranslateStream(sc *ParagraphScanner, w io.Writer, targetLang string) (string, error) {
+	convs := map[*Client]*Conversation{}
+	for n := 1; ; {
+		chunk, err := sc.Next()
+		if err == io.EOF {
+			return targetLang, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := io.WriteString(w, chunk.Separator); err != nil {
+			return "", err
+		}
+		if chunk.Text == "" {
+			continue
+		}
+		if targetLang == "" {
+			targetLang = t.defaultTarget(chunk.Text)
+		}
+
+		var translation string
+		if t.config.ContextWindow.Enabled {
+			// Keep one conversation per backend so context survives failover
+			_, err = t.hooked([]string{chunk.Text}, targetLang, func(c *Client, texts []string) ([][]*string, error) {
+				conv, ok := convs[c]
+				if !ok {
+					conv = c.NewConversation(targetLang)
+					convs[c] = conv
+				}
+				t.logger.Debug("calling API", "lang", targetLang, "paragraph", n, "backend", c.Name())
+				var err error
+				if translation, err = conv.Translate(texts[0]); err != nil {
+					return nil, err
+				}
+				return [][]*string{{&translation}}, nil
+			})
+		} else {
+			var result *Translation
+			if result, err = t.translateToLanguage(chunk.Text, targetLang); err == nil {
+				translation = result.Text
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("paragraph %d: %w", n, err)
+		}
+
+		if _, err := io.WriteString(w, sc.RestoreLineEndings(translation)); err != nil {
+			return "", err
+		}
+		n++
+	}
+}
+
+// defaultTarget returns the first priority language that differs from the
+// language of text
+func (t *Translator) defaultTarget(text string) string {
+	source := t.sourceLanguage(text)
+	for _, lang := range t.config.Languages.Priority {
+		if lang != source {
+			return lang
+		}
+	}
+	return t.config.Languages.Priority[0]
+}
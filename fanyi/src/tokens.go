@@ -0,0 +1,25 @@
+package src
+
+import "unicode"
+
+// EstimateTokens returns a rough local estimate of the token count of text.
+// CJK characters count as one token each, other characters as a quarter token.
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+		case unicode.IsSpace(r):
+			// whitespace is mostly merged into neighbouring tokens
+		default:
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -1,19 +1,28 @@
 package src
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Translator handles translation operations
 type Translator struct {
-	config *Config
-	client *Client
-	logger *slog.Logger
+	config  *Config
+	client  *Client
+	clients []*Client
+	cache   *Cache
+	hooks   *hookPipeline
+	logger  *slog.Logger
 }
 
+// cacheBackend is reported as the backend of translations served from the cache
+const cacheBackend = "cache"
+
 // NewTranslator creates a new translator instance
 func NewTranslator(cfg *Config) (*Translator, error) {
 	level := slog.LevelInfo
@@ -21,58 +30,267 @@ func NewTranslator(cfg *Config) (*Translator, error) {
 		level = slog.LevelDebug
 	}
 	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	for _, s := range cfg.Resolved() {
+		log.Debug("resolved config", "key", s.Key, "value", s.Value, "source", s.Source)
+	}
+
+	var cache *Cache
+	if cfg.Cache.Enabled {
+		cache = NewCache(time.Duration(cfg.Cache.TTL)*time.Hour, cfg.Cache.MaxEntries)
+	}
+
+	hooks, err := newHookPipeline(cfg.Hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	// The primary endpoint followed by the fallback chain
+	clients := []*Client{NewClient(cfg)}
+	for _, backend := range cfg.API.Fallbacks {
+		clients = append(clients, NewClient(cfg.withBackend(backend)))
+	}
 
 	return &Translator{
-		config: cfg,
-		client: NewClient(cfg),
-		logger: log,
+		config:  cfg,
+		client:  clients[0],
+		clients: clients,
+		cache:   cache,
+		hooks:   hooks,
+		logger:  log,
 	}, nil
 }
 
-// Translate translates text to the specified language(s)
-func (t *Translator) Translate(text string, targetLang string) (string, error) {
-	useColor := shouldUseColor()
+// Output is a result that can be rendered for the terminal or marshalled to JSON
+type Output interface {
+	Format(color bool) string
+}
+
+// Render formats out for stdout, using colours when stdout is a terminal
+func Render(out Output) string {
+	return out.Format(shouldUseColor())
+}
+
+// Result holds the translations of one input text
+type Result struct {
+	Original     string         `json:"original"`
+	Translations []*Translation `json:"translations"`
+
+	single bool
+}
+
+// Translation holds the translation of a text into one language
+type Translation struct {
+	Language     string        `json:"language"`
+	Text         string        `json:"text"`
+	Backend      string        `json:"backend,omitempty"`
+	Fallback     bool          `json:"fallback,omitempty"`
+	Romanization string        `json:"romanization,omitempty"`
+	Verification *Verification `json:"verification,omitempty"`
+}
 
+// Translate translates text to the specified language(s)
+func (t *Translator) Translate(text string, targetLang string) (*Result, error) {
 	// If target language specified, translate to that language only
 	if targetLang != "" {
-		translation, err := t.translateToLanguage(text, targetLang)
+		translation, err := t.translateOne(text, targetLang)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		return formatSingleOutput(text, translation, targetLang, useColor), nil
+		return &Result{Original: text, Translations: []*Translation{translation}, single: true}, nil
 	}
 
 	// Otherwise, translate to priority languages
-	var results []string
-	for _, lang := range t.config.Languages.Priority {
-		translation, err := t.translateToLanguage(text, lang)
+	return t.TranslateTo(text, t.config.Languages.Priority)
+}
+
+// TranslateTo translates text to each of the given languages, skipping the
+// languages that fail
+func (t *Translator) TranslateTo(text string, langs []string) (*Result, error) {
+	result := &Result{Original: text}
+	for _, lang := range langs {
+		translation, err := t.translateOne(text, lang)
 		if err != nil {
 			t.logger.Error("failed to translate", "lang", lang, "err", err)
 			continue
 		}
 
-		langName := getLanguageName(lang)
-		results = append(results, formatLine(langName, translation, useColor))
+		result.Translations = append(result.Translations, translation)
+	}
+
+	if len(result.Translations) == 0 {
+		return nil, fmt.Errorf("failed to translate to any language")
+	}
+
+	return result, nil
+}
+
+// Format renders the result for the terminal
+func (r *Result) Format(color bool) string {
+	if r.single && len(r.Translations) == 1 {
+		return formatSingleOutput(r.Original, r.Translations[0], color)
+	}
+	lines := make([]string, 0, len(r.Translations))
+	for _, translation := range r.Translations {
+		lines = append(lines, formatTranslationLine(translation, color))
+	}
+	return formatMultiOutput(r.Original, lines, color)
+}
+
+// translateOne translates text to a language, romanizes and verifies the
+// result if enabled. Romanization and verification see the text as the
+// model did, between the pre and the post hooks.
+func (t *Translator) translateOne(text, lang string) (*Translation, error) {
+	var result *Translation
+	_, err := t.hooked([]string{text}, lang, func(c *Client, texts []string) ([][]*string, error) {
+		var err error
+		if result, err = t.translateWith(c, texts[0], lang); err != nil {
+			return nil, err
+		}
+
+		if t.config.Advanced.Romanize && romanizationSystem(lang) != "" {
+			if r, err := t.romanize(result.Text, lang); err != nil {
+				t.logger.Warn("romanization failed", "lang", lang, "err", err)
+			} else {
+				result.Romanization = r
+			}
+		}
+
+		if t.config.Verify.Enabled {
+			if source := t.sourceLanguage(text); source == lang {
+				t.logger.Debug("skipping verification", "lang", lang, "reason", "same as source")
+			} else if v, err := t.verify(texts[0], result.Text, lang, result.Backend); err != nil {
+				t.logger.Warn("verification failed", "lang", lang, "err", err)
+			} else {
+				result.Verification = v
+			}
+		}
+		return [][]*string{{&result.Text}}, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	if len(results) == 0 {
-		return "", fmt.Errorf("failed to translate to any language")
+// sourceLanguage returns the configured source language or detects it from text
+func (t *Translator) sourceLanguage(text string) string {
+	if t.config.Languages.Source != "" {
+		return t.config.Languages.Source
 	}
+	return detectLanguage(text)
+}
 
-	output := formatMultiOutput(text, results, useColor)
-	return output, nil
+// translateToLanguage translates text to a specific language, passing the
+// text through the pre hooks before and the translation through the post
+// hooks after the request
+func (t *Translator) translateToLanguage(text, lang string) (*Translation, error) {
+	var result *Translation
+	_, err := t.hooked([]string{text}, lang, func(c *Client, texts []string) ([][]*string, error) {
+		var err error
+		if result, err = t.translateWith(c, texts[0], lang); err != nil {
+			return nil, err
+		}
+		return [][]*string{{&result.Text}}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// translateToLanguage translates text to a specific language
-func (t *Translator) translateToLanguage(text, lang string) (string, error) {
+// translateWith translates text to a specific language with backend c,
+// using the cache
+func (t *Translator) translateWith(c *Client, text, lang string) (*Translation, error) {
+	key := t.cacheKey(text, lang)
+	if translation, ok := t.cache.Get(key); ok {
+		t.logger.Debug("cache hit", "lang", lang)
+		return &Translation{Language: lang, Text: translation, Backend: cacheBackend}, nil
+	}
 
-	// Translate via API
-	t.logger.Debug("calling API", "lang", lang)
-	translation, err := t.client.Translate(text, lang)
+	var translation string
+	var err error
+	// Translate paragraph by paragraph, carrying context between requests
+	if paragraphs := splitParagraphs(text); t.config.ContextWindow.Enabled && len(paragraphs) > 1 {
+		translation, err = t.translateDocument(c, paragraphs, lang)
+	} else {
+		t.logger.Debug("calling API", "lang", lang, "backend", c.Name())
+		translation, err = c.Translate(text, lang)
+	}
 	if err != nil {
-		return "", fmt.Errorf("translation failed: %w", err)
+		return nil, err
+	}
+	t.cache.Set(key, translation)
+	return &Translation{
+		Language: lang,
+		Text:     translation,
+		Backend:  c.Name(),
+		Fallback: c != t.client,
+	}, nil
+}
+
+// cacheKey identifies a translation by everything that affects its output
+func (t *Translator) cacheKey(text, lang string) string {
+	return CacheKey(t.config.API.Model, t.config.Advanced.Style, t.config.Advanced.Context,
+		strconv.FormatBool(t.config.ContextWindow.Enabled), lang, text)
+}
+
+// translateDocument translates paragraphs in order within one conversation
+func (t *Translator) translateDocument(c *Client, paragraphs []string, lang string) (string, error) {
+	conv := c.NewConversation(lang)
+	translations := make([]string, 0, len(paragraphs))
+	for i, p := range paragraphs {
+		t.logger.Debug("calling API", "lang", lang, "chunk", i+1, "total", len(paragraphs), "backend", c.Name())
+		translation, err := conv.Translate(p)
+		if err != nil {
+			return "", fmt.Errorf("translation of chunk %d failed: %w", i+1, err)
+		}
+		translations = append(translations, translation)
+	}
+	return strings.Join(translations, "\n\n"), nil
+}
+
+// failover calls fn with each backend in order until one succeeds or fails
+// with an error that is not retryable, and returns the backend that succeeded
+func (t *Translator) failover(fn func(c *Client) error) (*Client, error) {
+	return t.failoverClients(t.clients, fn)
+}
+
+// failoverFrom is failover starting with the backend named first, such as
+// the one that produced the translation being checked
+func (t *Translator) failoverFrom(first string, fn func(c *Client) error) (*Client, error) {
+	clients := make([]*Client, 0, len(t.clients))
+	for _, c := range t.clients {
+		if c.Name() == first {
+			clients = append(clients, c)
+		}
+	}
+	for _, c := range t.clients {
+		if c.Name() != first {
+			clients = append(clients, c)
+		}
 	}
-	return translation, nil
+	return t.failoverClients(clients, fn)
+}
+
+func (t *Translator) failoverClients(clients []*Client, fn func(c *Client) error) (*Client, error) {
+	var errs []error
+	for i, c := range clients {
+		err := fn(c)
+		if err == nil {
+			return c, nil
+		}
+		if len(clients) == 1 {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+		if !IsRetryable(err) {
+			break
+		}
+		if i < len(clients)-1 {
+			t.logger.Warn("backend failed, trying next", "backend", c.Name(), "err", err)
+		}
+	}
+	return nil, errors.Join(errs...)
 }
 
 // Close closes the translator and its resources
@@ -126,9 +344,11 @@ func getLanguageName(code string) string {
 const (
 	colorReset   = "\033[0m"
 	colorGreen   = "\033[32m"
+	colorYellow  = "\033[33m"
 	colorMagenta = "\033[35m"
 	colorGray    = "\033[90m"
 	colorBold    = "\033[1m"
+	colorDim     = "\033[2m"
 )
 
 func shouldUseColor() bool {
@@ -149,8 +369,8 @@ func c(s, code string, enable bool) string {
 	return code + s + colorReset
 }
 
-func formatSingleOutput(original, translation, lang string, color bool) string {
-	langName := getLanguageName(lang)
+func formatSingleOutput(original string, translation *Translation, color bool) string {
+	langName := getLanguageName(translation.Language)
 	var b strings.Builder
 	b.WriteString(c("Original", colorGray+colorBold, color))
 	b.WriteString(": ")
@@ -158,7 +378,10 @@ func formatSingleOutput(original, translation, lang string, color bool) string {
 	b.WriteString("\n")
 	b.WriteString(c(langName+":", colorGreen+colorBold, color))
 	b.WriteString(" ")
-	b.WriteString(translation)
+	b.WriteString(translation.Text)
+	b.WriteString(formatBackend(translation, color))
+	b.WriteString(formatRomanization(translation, color))
+	b.WriteString(formatVerification(translation.Verification, color))
 	return b.String()
 }
 
@@ -177,3 +400,39 @@ func formatMultiOutput(original string, lines []string, color bool) string {
 func formatLine(langName, translation string, color bool) string {
 	return fmt.Sprintf("%s %s %s", c("•", colorMagenta, color), c(langName+":", colorGreen+colorBold, color), translation)
 }
+
+func formatTranslationLine(translation *Translation, color bool) string {
+	line := formatLine(getLanguageName(translation.Language), translation.Text, color)
+	return line + formatBackend(translation, color) + formatRomanization(translation, color) +
+		formatVerification(translation.Verification, color)
+}
+
+// formatBackend notes the backend of translations produced by a fallback
+func formatBackend(translation *Translation, color bool) string {
+	if !translation.Fallback {
+		return ""
+	}
+	return " " + c("(via "+translation.Backend+")", colorDim, color)
+}
+
+func formatVerification(v *Verification, color bool) string {
+	if v == nil {
+		return ""
+	}
+	var b strings.Builder
+	summary := fmt.Sprintf("confidence %.2f", v.Confidence)
+	low := v.LowConfidenceSegments()
+	if len(low) == 0 {
+		b.WriteString("\n  " + c("✓ "+summary, colorDim, color))
+		if len(v.Segments) == 1 {
+			b.WriteString("\n  " + c("↩ "+v.Segments[0].BackTranslation, colorDim, color))
+		}
+		return b.String()
+	}
+	b.WriteString("\n  " + c(fmt.Sprintf("⚠ %s, %d low-confidence segment(s)", summary, len(low)), colorYellow, color))
+	for _, s := range low {
+		b.WriteString("\n    " + c(fmt.Sprintf("[%.2f] ", s.Confidence), colorYellow+colorBold, color) + s.Translation)
+		b.WriteString("\n    " + c("↩ "+s.BackTranslation, colorDim, color))
+	}
+	return b.String()
+}
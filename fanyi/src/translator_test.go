@@ -0,0 +1,90 @@
+package src
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newStatusServer starts a server that always answers with status
+func newStatusServer(t *testing.T, status int, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, http.StatusText(status), status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFailoverOnRetryableErrors(t *testing.T) {
+	var downCalls, limitedCalls atomic.Int32
+	down := newStatusServer(t, http.StatusBadGateway, &downCalls)
+	limited := newStatusServer(t, http.StatusTooManyRequests, &limitedCalls)
+	healthy, _ := newTestServer(t, func(ChatRequest) string { return "你好" })
+
+	cfg := testConfig(down.URL)
+	cfg.Cache.Enabled = false
+	cfg.API.Fallbacks = []BackendConfig{
+		{Name: "limited", Endpoint: limited.URL, Key: "k"},
+		{Name: "healthy", Endpoint: healthy.URL, Key: "k", Model: "gpt-4o-mini"},
+	}
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.Translate("hello", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := result.Translations[0]
+	if got.Text != "你好" || got.Backend != "healthy" || !got.Fallback {
+		t.Errorf("unexpected translation: %+v", got)
+	}
+	if downCalls.Load() != 1 || limitedCalls.Load() != 1 {
+		t.Errorf("calls = %d, %d, want 1, 1", downCalls.Load(), limitedCalls.Load())
+	}
+	if out := result.Format(false); !strings.Contains(out, "(via healthy)") {
+		t.Errorf("output does not name the backend: %q", out)
+	}
+}
+
+func TestFailoverOnTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+	healthy, _ := newTestServer(t, func(ChatRequest) string { return "你好" })
+
+	cfg := testConfig(slow.URL)
+	cfg.API.Timeout = 1
+	cfg.API.Fallbacks = []BackendConfig{{Endpoint: healthy.URL, Key: "k"}}
+	trans, _ := NewTranslator(cfg)
+
+	result, err := trans.Translate("hello", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "gpt-4@" + strings.TrimPrefix(healthy.URL, "http://"); result.Translations[0].Backend != want {
+		t.Errorf("backend = %q, want %q", result.Translations[0].Backend, want)
+	}
+}
+
+func TestNoFailoverOnClientErrors(t *testing.T) {
+	var badCalls, nextCalls atomic.Int32
+	bad := newStatusServer(t, http.StatusBadRequest, &badCalls)
+	next := newStatusServer(t, http.StatusOK, &nextCalls)
+
+	cfg := testConfig(bad.URL)
+	cfg.API.Fallbacks = []BackendConfig{{Endpoint: next.URL, Key: "k"}}
+	trans, _ := NewTranslator(cfg)
+
+	if _, err := trans.Translate("hello", "zh"); err == nil {
+		t.Fatal("expected error")
+	}
+	if nextCalls.Load() != 0 {
+		t.Error("fell through on a non-retryable error")
+	}
+}
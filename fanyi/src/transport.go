@@ -0,0 +1,65 @@
+package src
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// newTransport builds the HTTP transport for the API settings: proxy, CA
+// bundle, client certificate and Unix socket
+func newTransport(api APIConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if api.Proxy != "" {
+		proxy, err := url.Parse(api.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", api.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if api.CACert != "" || api.ClientCert != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if api.CACert != "" {
+			pem, err := os.ReadFile(api.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", api.CACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if api.ClientCert != "" {
+			cert, err := tls.LoadX509KeyPair(api.ClientCert, api.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if api.UnixSocket != "" {
+		// Requests keep the endpoint URL for the path and Host header but
+		// are all dialled over the socket
+		socket := api.UnixSocket
+		var dialer net.Dialer
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	return transport, nil
+}
@@ -0,0 +1,262 @@
+package src
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// chatHandler answers every request with a fixed translation and passes the
+// request to check
+func chatHandler(check func(r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []Choice{{Message: Message{Role: "assistant", Content: TextContent("你好")}}},
+		})
+	}
+}
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestClientCustomCA(t *testing.T) {
+	srv := httptest.NewUnstartedServer(chatHandler(nil))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig(srv.URL)
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err == nil {
+		t.Fatal("expected certificate error without CA bundle")
+	}
+
+	cfg.API.CACert = writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fanyi"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	srv := httptest.NewUnstartedServer(chatHandler(nil))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	cfg := testConfig(srv.URL)
+	cfg.API.CACert = writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err == nil {
+		t.Fatal("expected handshake error without client certificate")
+	}
+
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	cfg.API.ClientCert = writePEM(t, dir, "client.pem", "CERTIFICATE", der)
+	cfg.API.ClientKey = writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientHeadersAndQuery(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewTLSServer(chatHandler(func(r *http.Request) { got = r }))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig(srv.URL + "/v1/chat/completions?foo=bar")
+	cfg.API.CACert = writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	cfg.API.Headers = map[string]string{"OpenAI-Organization": "org-123", "api-key": "azure-key"}
+	cfg.API.Query = map[string]string{"api-version": "2024-06-01"}
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Header.Get("OpenAI-Organization") != "org-123" || got.Header.Get("Api-Key") != "azure-key" {
+		t.Errorf("missing headers: %v", got.Header)
+	}
+	if q := got.URL.Query(); q.Get("api-version") != "2024-06-01" || q.Get("foo") != "bar" {
+		t.Errorf("unexpected query: %s", got.URL.RawQuery)
+	}
+}
+
+func TestClientProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(chatHandler(func(r *http.Request) { proxied = r.URL.String() }))
+	t.Cleanup(proxy.Close)
+
+	cfg := testConfig("http://llm.internal/v1/chat/completions")
+	cfg.API.Proxy = proxy.URL
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://llm.internal/v1/chat/completions" {
+		t.Errorf("proxy saw %q", proxied)
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "fanyi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "llm.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	var path string
+	srv := httptest.NewUnstartedServer(chatHandler(func(r *http.Request) { path = r.URL.Path }))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig("http://localhost/v1/chat/completions")
+	cfg.API.UnixSocket = socket
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v1/chat/completions" {
+		t.Errorf("server saw path %q", path)
+	}
+}
+
+func TestValidateTransport(t *testing.T) {
+	cfg := testConfig("https://example.com")
+	cfg.API.CACert = filepath.Join(t.TempDir(), "missing.pem")
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for missing CA bundle")
+	}
+
+	cfg = testConfig("https://example.com")
+	cfg.API.ClientCert = "client.pem"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for client certificate without key")
+	}
+}
+
+func TestClientAzureDeployment(t *testing.T) {
+	var (
+		got  *http.Request
+		body ChatRequest
+	)
+	srv := httptest.NewServer(chatHandler(func(r *http.Request) {
+		got = r
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig(srv.URL + "/")
+	cfg.API.Provider = ProviderAzure
+	cfg.API.Deployment = "gpt4o-prod"
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Path != "/openai/deployments/gpt4o-prod/chat/completions" {
+		t.Errorf("unexpected path %q", got.URL.Path)
+	}
+	if v := got.URL.Query().Get("api-version"); v != defaultAzureAPIVersion {
+		t.Errorf("api-version = %q", v)
+	}
+	if got.Header.Get("api-key") != "test" || got.Header.Get("Authorization") != "" {
+		t.Errorf("unexpected auth headers: %v", got.Header)
+	}
+	if body.Model != "" {
+		t.Errorf("model sent to Azure: %q", body.Model)
+	}
+
+	// A full deployment URL is used as is
+	cfg.API.Endpoint = srv.URL + "/openai/deployments/other/chat/completions?api-version=2025-01-01-preview"
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.Path != "/openai/deployments/other/chat/completions" || got.URL.Query().Get("api-version") != "2025-01-01-preview" {
+		t.Errorf("unexpected URL %s", got.URL)
+	}
+}
+
+func TestClientGatewayModel(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(chatHandler(func(r *http.Request) { json.NewDecoder(r.Body).Decode(&body) }))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig(srv.URL)
+	cfg.API.ModelMap = map[string]string{"gpt-4": "corp/gpt-4-turbo"}
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if body["model"] != "corp/gpt-4-turbo" {
+		t.Errorf("model = %v, want remapped", body["model"])
+	}
+
+	cfg.API.OmitModel = true
+	body = nil
+	if _, err := NewClient(cfg).Translate("hello", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["model"]; ok {
+		t.Errorf("model was not omitted: %v", body)
+	}
+}
+
+func TestValidateProvider(t *testing.T) {
+	cfg := testConfig("https://example.openai.azure.com")
+	cfg.API.Provider = "bedrock"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+	cfg.API.Provider = ProviderAzure
+	cfg.API.Model = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for Azure without deployment")
+	}
+	cfg.API.Deployment = "prod"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
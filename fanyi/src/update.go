@@ -0,0 +1,233 @@
+package src
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Paragraph change kinds reported by Update
+const (
+	ChangeAdded   = "added"
+	ChangeChanged = "changed"
+	ChangeRemoved = "removed"
+)
+
+// UpdateSummary describes how a source document changed and what was
+// re-translated
+type UpdateSummary struct {
+	Language  string             `json:"language"`
+	Unchanged int                `json:"unchanged"`
+	Changed   int                `json:"changed"`
+	Added     int                `json:"added"`
+	Removed   int                `json:"removed"`
+	Changes   []*ParagraphChange `json:"changes,omitempty"`
+}
+
+// ParagraphChange is one changed paragraph; Old and New are 1-based
+// paragraph numbers, 0 when the paragraph does not exist on that side
+type ParagraphChange struct {
+	Kind string `json:"kind"`
+	Old  int    `json:"old,omitempty"`
+	New  int    `json:"new,omitempty"`
+	Text string `json:"text"`
+}
+
+// Update writes the translation of newSource to w, reusing the paragraphs of
+// oldTarget, the translation of oldSource, for paragraphs that did not change
+// and translating only changed and new paragraphs. The paragraphs of
+// oldSource and oldTarget must correspond one to one. If targetLang is empty
+// it is detected from oldTarget.
+func (t *Translator) Update(oldSource, newSource, oldTarget io.Reader, w io.Writer, targetLang string) (*UpdateSummary, error) {
+	oldChunks, _, err := readChunks(oldSource)
+	if err != nil {
+		return nil, fmt.Errorf("old source: %w", err)
+	}
+	newChunks, newScanner, err := readChunks(newSource)
+	if err != nil {
+		return nil, fmt.Errorf("new source: %w", err)
+	}
+	targetChunks, _, err := readChunks(oldTarget)
+	if err != nil {
+		return nil, fmt.Errorf("old translation: %w", err)
+	}
+
+	oldParas, newParas, targetParas := paragraphs(oldChunks), paragraphs(newChunks), paragraphs(targetChunks)
+	if len(oldParas) != len(targetParas) {
+		return nil, fmt.Errorf("old source has %d paragraphs but old translation has %d, cannot match them",
+			len(oldParas), len(targetParas))
+	}
+	if targetLang == "" {
+		targetLang = detectLanguage(strings.Join(targetParas, "\n"))
+	}
+
+	summary := &UpdateSummary{Language: targetLang}
+	translations := make([]string, len(newParas))
+	for _, op := range diffParagraphs(oldParas, newParas) {
+		if op.old >= 0 && op.new >= 0 && !op.changed {
+			translations[op.new] = targetParas[op.old]
+			summary.Unchanged++
+			continue
+		}
+
+		change := &ParagraphChange{Old: op.old + 1, New: op.new + 1}
+		switch {
+		case op.changed:
+			change.Kind, change.Text = ChangeChanged, newParas[op.new]
+			summary.Changed++
+		case op.new >= 0:
+			change.Kind, change.Text = ChangeAdded, newParas[op.new]
+			summary.Added++
+		default:
+			change.Kind, change.Text = ChangeRemoved, oldParas[op.old]
+			summary.Removed++
+		}
+		summary.Changes = append(summary.Changes, change)
+
+		if op.new >= 0 {
+			t.logger.Debug("translating paragraph", "paragraph", op.new+1, "kind", change.Kind)
+			result, err := t.translateToLanguage(newParas[op.new], targetLang)
+			if err != nil {
+				return nil, fmt.Errorf("paragraph %d: %w", op.new+1, err)
+			}
+			translations[op.new] = result.Text
+		}
+	}
+
+	// Keep the layout of the new source
+	var b strings.Builder
+	i := 0
+	for _, chunk := range newChunks {
+		b.WriteString(chunk.Separator)
+		if chunk.Text != "" {
+			b.WriteString(newScanner.RestoreLineEndings(translations[i]))
+			i++
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// readChunks reads all chunks of r
+func readChunks(r io.Reader) ([]Chunk, *ParagraphScanner, error) {
+	sc := NewParagraphScanner(r)
+	var chunks []Chunk
+	for {
+		chunk, err := sc.Next()
+		if err == io.EOF {
+			return chunks, sc, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+// paragraphs returns the non-empty texts of chunks
+func paragraphs(chunks []Chunk) []string {
+	var texts []string
+	for _, chunk := range chunks {
+		if chunk.Text != "" {
+			texts = append(texts, chunk.Text)
+		}
+	}
+	return texts
+}
+
+// diffOp relates an old and a new paragraph; -1 marks a missing side.
+// Changed ops pair a removed paragraph with the added one replacing it.
+type diffOp struct {
+	old, new int
+	changed  bool
+}
+
+// diffParagraphs aligns two paragraph lists by their longest common
+// subsequence, ignoring surrounding whitespace
+func diffParagraphs(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	equal := func(i, j int) bool { return strings.TrimSpace(a[i]) == strings.TrimSpace(b[j]) }
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if equal(i, j) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	var removed, added []int
+	// flush reports the removals and additions between two matches, pairing
+	// them up as changes
+	flush := func() {
+		for k := 0; k < max(len(removed), len(added)); k++ {
+			op := diffOp{old: -1, new: -1}
+			if k < len(removed) {
+				op.old = removed[k]
+			}
+			if k < len(added) {
+				op.new = added[k]
+			}
+			op.changed = op.old >= 0 && op.new >= 0
+			ops = append(ops, op)
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && equal(i, j):
+			flush()
+			ops = append(ops, diffOp{old: i, new: j})
+			i, j = i+1, j+1
+		case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, j)
+			j++
+		default:
+			removed = append(removed, i)
+			i++
+		}
+	}
+	flush()
+	return ops
+}
+
+// Format renders the summary for the terminal
+func (s *UpdateSummary) Format(color bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d unchanged, %d changed, %d added, %d removed",
+		c(getLanguageName(s.Language)+":", colorGreen+colorBold, color), s.Unchanged, s.Changed, s.Added, s.Removed)
+	for _, ch := range s.Changes {
+		var marker, where string
+		switch ch.Kind {
+		case ChangeAdded:
+			marker, where = c("+", colorGreen, color), fmt.Sprintf("¶%d", ch.New)
+		case ChangeRemoved:
+			marker, where = c("-", colorYellow, color), fmt.Sprintf("¶%d", ch.Old)
+		default:
+			marker, where = c("~", colorMagenta, color), fmt.Sprintf("¶%d→¶%d", ch.Old, ch.New)
+		}
+		fmt.Fprintf(&b, "\n  %s %s %s", marker, c(where, colorGray, color), preview(ch.Text, 60))
+	}
+	return b.String()
+}
+
+// preview returns the first line of text, shortened to at most n runes
+func preview(text string, n int) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if r := []rune(line); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return line
+}
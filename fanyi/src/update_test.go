@@ -0,0 +1,67 @@
+package src
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffParagraphs(t *testing.T) {
+	ops := diffParagraphs([]string{"a", "b", "c", "d"}, []string{"a", "B", "c", "d", "e"})
+	var got []string
+	for _, op := range ops {
+		switch {
+		case op.changed:
+			got = append(got, "~")
+		case op.old < 0:
+			got = append(got, "+")
+		case op.new < 0:
+			got = append(got, "-")
+		default:
+			got = append(got, "=")
+		}
+	}
+	if strings.Join(got, "") != "=~==+" {
+		t.Errorf("got ops %q, want %q", strings.Join(got, ""), "=~==+")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	srv, requests := newTestServer(t, func(req ChatRequest) string {
+		prompt := req.Messages[len(req.Messages)-1].Content.Text
+		return "译:" + prompt[strings.LastIndex(prompt, "Text: ")+len("Text: "):]
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Cache.Enabled = false
+	trans, _ := NewTranslator(cfg)
+
+	oldSource := "# Title\n\nFirst paragraph.\n\nSecond paragraph.\n\nObsolete note.\n"
+	newSource := "# Title\r\n\r\nFirst paragraph, revised.\r\n\r\nSecond paragraph.\r\n\r\nNew ending.\r\n"
+	oldTarget := "# 标题\n\n第一段。\n\n第二段。\n\n过时的说明。\n"
+
+	var out strings.Builder
+	summary, err := trans.Update(strings.NewReader(oldSource), strings.NewReader(newSource),
+		strings.NewReader(oldTarget), &out, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# 标题\r\n\r\n译:First paragraph, revised.\r\n\r\n第二段。\r\n\r\n译:New ending.\r\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	if summary.Language != "zh" || summary.Unchanged != 2 || summary.Changed != 2 || summary.Added != 0 || summary.Removed != 0 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if len(*requests) != 2 {
+		t.Errorf("got %d requests, want 2", len(*requests))
+	}
+}
+
+func TestUpdateMismatchedTranslation(t *testing.T) {
+	trans, _ := NewTranslator(testConfig("http://localhost"))
+	_, err := trans.Update(strings.NewReader("a\n\nb\n"), strings.NewReader("a\n"),
+		strings.NewReader("甲\n"), &strings.Builder{}, "zh")
+	if err == nil || !strings.Contains(err.Error(), "cannot match") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package src
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Verification holds the back-translation quality check of a translation
+type Verification struct {
+	SourceLanguage string    `json:"source_language"`
+	Confidence     float64   `json:"confidence"`
+	Segments       []Segment `json:"segments"`
+}
+
+// Segment holds the quality scores of one translated paragraph
+type Segment struct {
+	Source          string  `json:"source"`
+	Translation     string  `json:"translation"`
+	BackTranslation string  `json:"back_translation"`
+	Similarity      float64 `json:"similarity"`
+	Adequacy        float64 `json:"adequacy"`
+	Confidence      float64 `json:"confidence"`
+	LowConfidence   bool    `json:"low_confidence"`
+}
+
+// LowConfidenceSegments returns the segments scored below the threshold
+func (v *Verification) LowConfidenceSegments() []Segment {
+	var low []Segment
+	for _, s := range v.Segments {
+		if s.LowConfidence {
+			low = append(low, s)
+		}
+	}
+	return low
+}
+
+// verify back-translates translation and scores it against the original text,
+// preferring backend, the one that produced the translation
+func (t *Translator) verify(text, translation, lang, backend string) (*Verification, error) {
+	source := t.sourceLanguage(text)
+
+	// Align paragraphs when the translation kept the structure, otherwise
+	// score the text as a whole
+	sources, translations := splitParagraphs(text), splitParagraphs(translation)
+	if len(sources) != len(translations) || len(sources) == 0 {
+		sources, translations = []string{text}, []string{translation}
+	}
+
+	v := &Verification{SourceLanguage: source}
+	total := 0.0
+	for i := range sources {
+		seg, err := t.verifySegment(sources[i], translations[i], source, lang, backend)
+		if err != nil {
+			return nil, err
+		}
+		total += seg.Confidence
+		v.Segments = append(v.Segments, seg)
+	}
+	v.Confidence = total / float64(len(v.Segments))
+	return v, nil
+}
+
+// verifySegment scores a single source/translation pair
+func (t *Translator) verifySegment(source, translation, sourceLang, lang, backend string) (Segment, error) {
+	t.logger.Debug("calling API", "lang", sourceLang, "check", "back-translation")
+	var back string
+	_, err := t.failoverFrom(backend, func(c *Client) error {
+		var err error
+		back, err = c.Translate(translation, sourceLang)
+		return err
+	})
+	if err != nil {
+		return Segment{}, fmt.Errorf("back-translation failed: %w", err)
+	}
+
+	seg := Segment{
+		Source:          source,
+		Translation:     translation,
+		BackTranslation: back,
+		Similarity:      similarity(source, back),
+	}
+	seg.Confidence = seg.Similarity
+
+	t.logger.Debug("calling API", "lang", lang, "check", "adequacy")
+	var adequacy float64
+	_, err = t.failoverFrom(backend, func(c *Client) error {
+		var err error
+		adequacy, err = c.RateAdequacy(source, translation, lang)
+		return err
+	})
+	if err != nil {
+		t.logger.Warn("adequacy rating failed", "lang", lang, "err", err)
+	} else {
+		seg.Adequacy = adequacy
+		seg.Confidence = (seg.Similarity + adequacy) / 2
+	}
+
+	seg.LowConfidence = seg.Confidence < t.config.Verify.Threshold
+	return seg, nil
+}
+
+var scoreRe = regexp.MustCompile(`[1-5](\.\d+)?`)
+
+// RateAdequacy asks the model to rate how well translation conveys source,
+// normalised to the range 0-1
+func (c *Client) RateAdequacy(source, translation, targetLanguage string) (float64, error) {
+	prompt := fmt.Sprintf(`Rate how accurately and completely the %s translation conveys the meaning of the source text.
+Use a scale from 1 (meaning lost) to 5 (perfect). Reply with the number only.
+
+Source:
+%s
+
+Translation:
+%s`, getLanguageName(targetLanguage), source, translation)
+
+	resp, err := c.Chat([]Message{{Role: "user", Content: TextContent(prompt)}})
+	if err != nil {
+		return 0, err
+	}
+
+	answer := resp.Choices[0].Message.Content.Text
+	match := scoreRe.FindString(answer)
+	if match == "" {
+		return 0, fmt.Errorf("no score in answer %q", answer)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, err
+	}
+	return (score - 1) / 4, nil
+}
+
+// similarity returns the Dice coefficient of the character bigrams of a and b,
+// ignoring case, whitespace and punctuation
+func similarity(a, b string) float64 {
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 && len(bb) == 0 {
+		return 1
+	}
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(ba))
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+// bigrams returns the character bigrams of the normalised text
+func bigrams(s string) []string {
+	var runes []rune
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+	if len(runes) == 1 {
+		return []string{string(runes)}
+	}
+	var grams []string
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// detectLanguage guesses the language of text from the scripts it uses
+func detectLanguage(text string) string {
+	counts := map[string]int{}
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["en"]++
+		}
+	}
+
+	// Kana is decisive over the Han characters Japanese shares with Chinese
+	if counts["ja"] > 0 {
+		return "ja"
+	}
+
+	best, bestCount := "en", 0
+	for _, lang := range []string{"zh", "ko", "ru", "ar", "th", "en"} {
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	return best
+}
@@ -0,0 +1,93 @@
+package src
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	if got := similarity("Hello, World!", "hello world"); got != 1 {
+		t.Errorf("identical text scored %.2f", got)
+	}
+	if got := similarity("我有一个苹果", "我有一个香蕉"); got <= 0.3 || got >= 1 {
+		t.Errorf("partial CJK match scored %.2f", got)
+	}
+	if got := similarity("apple", "banana"); got != 0 {
+		t.Errorf("unrelated text scored %.2f", got)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"hello world":  "en",
+		"你好世界":         "zh",
+		"今日は良い天気ですね":   "ja",
+		"안녕하세요":        "ko",
+		"Привет, мир":  "ru",
+		"hello 世界，你好呀": "zh",
+	}
+	for text, want := range tests {
+		if got := detectLanguage(text); got != want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestVerifyFlagsLowConfidence(t *testing.T) {
+	srv, _ := newTestServer(t, func(req ChatRequest) string {
+		last := req.Messages[len(req.Messages)-1].Content.Text
+		switch {
+		case strings.HasPrefix(last, "Rate how accurately"):
+			return "2"
+		case strings.Contains(last, "to English"):
+			return "Something else entirely"
+		}
+		return "会议改到周四"
+	})
+	cfg := testConfig(srv.URL)
+	cfg.Verify.Enabled = true
+	trans, _ := NewTranslator(cfg)
+
+	tr, err := trans.translateOne("The meeting moved to Thursday", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Verification == nil || len(tr.Verification.LowConfidenceSegments()) != 1 {
+		t.Fatalf("expected one low-confidence segment, got %+v", tr.Verification)
+	}
+	if seg := tr.Verification.Segments[0]; seg.Adequacy != 0.25 {
+		t.Errorf("adequacy = %.2f, want 0.25", seg.Adequacy)
+	}
+}
+
+func TestVerifyUsesTranslatingBackend(t *testing.T) {
+	var downCalls atomic.Int32
+	down := newStatusServer(t, http.StatusBadGateway, &downCalls)
+	healthy, requests := newTestServer(t, func(req ChatRequest) string {
+		last := req.Messages[len(req.Messages)-1].Content.Text
+		switch {
+		case strings.HasPrefix(last, "Rate how accurately"):
+			return "5"
+		case strings.Contains(last, "to English"):
+			return "The meeting moved to Thursday"
+		}
+		return "会议改到周四"
+	})
+	cfg := testConfig(down.URL)
+	cfg.Verify.Enabled = true
+	cfg.API.Fallbacks = []BackendConfig{{Name: "healthy", Endpoint: healthy.URL, Key: "k"}}
+	trans, _ := NewTranslator(cfg)
+
+	tr, err := trans.translateOne("The meeting moved to Thursday", "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Verification == nil || tr.Verification.Segments[0].Adequacy != 1 {
+		t.Fatalf("verification did not run on the fallback: %+v", tr.Verification)
+	}
+	if downCalls.Load() != 1 || len(*requests) != 3 {
+		t.Errorf("primary got %d calls, fallback %d, want 1 and 3", downCalls.Load(), len(*requests))
+	}
+}
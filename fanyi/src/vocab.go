@@ -0,0 +1,300 @@
+package src
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vocabFile is the name of the vocabulary notebook in the config directory
+const vocabFile = "vocab.json"
+
+// SM-2 ease factors: the starting ease of a card and the lowest it can drop to
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+)
+
+// VocabCard is a saved translation with its review schedule
+type VocabCard struct {
+	ID       int       `json:"id"`
+	Front    string    `json:"front"`
+	Back     string    `json:"back"`
+	Language string    `json:"language"`
+	Kind     string    `json:"kind,omitempty"`
+	Added    time.Time `json:"added"`
+
+	// SM-2 state: the card is shown again on Due, Interval days after the
+	// last review; Repetitions counts the successful reviews in a row
+	Due         time.Time `json:"due"`
+	Interval    int       `json:"interval"`
+	Repetitions int       `json:"repetitions"`
+	Ease        float64   `json:"ease"`
+}
+
+// Review schedules the next review of the card after an answer graded from
+// 0 (forgotten) to 5 (perfect), following SM-2
+func (card *VocabCard) Review(quality int, now time.Time) {
+	quality = min(max(quality, 0), 5)
+	if quality < 3 {
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.Interval = 1
+		case 1:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.Ease))
+		}
+		card.Repetitions++
+	}
+	miss := float64(5 - quality)
+	card.Ease = max(card.Ease+0.1-miss*(0.08+miss*0.02), minEase)
+	card.Due = now.AddDate(0, 0, card.Interval)
+}
+
+// Vocab is the vocabulary notebook, stored as one JSON file
+type Vocab struct {
+	path   string
+	NextID int          `json:"next_id"`
+	Cards  []*VocabCard `json:"cards"`
+}
+
+// VocabPath returns the location of the notebook next to the config file
+func VocabPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, vocabFile), nil
+}
+
+// OpenVocab reads the notebook at path; a missing file is an empty notebook
+func OpenVocab(path string) (*Vocab, error) {
+	v := &Vocab{path: path, NextID: 1}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("failed to parse vocabulary %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// Path returns the location of the notebook
+func (v *Vocab) Path() string {
+	return v.path
+}
+
+// Save writes the notebook back to its file
+func (v *Vocab) Save() error {
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(v.path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write vocabulary: %w", err)
+	}
+	return nil
+}
+
+// Add saves a translation as a card due now. Saving the same text and
+// language again replaces the translation but keeps the review schedule.
+func (v *Vocab) Add(front, back, language, kind string, now time.Time) *VocabCard {
+	for _, card := range v.Cards {
+		if card.Front == front && card.Language == language {
+			card.Back = back
+			card.Kind = kind
+			return card
+		}
+	}
+	card := &VocabCard{
+		ID:       v.NextID,
+		Front:    front,
+		Back:     back,
+		Language: language,
+		Kind:     kind,
+		Added:    now,
+		Due:      now,
+		Ease:     initialEase,
+	}
+	v.NextID++
+	v.Cards = append(v.Cards, card)
+	return card
+}
+
+// AddEntry saves each output of a history entry as a card
+func (v *Vocab) AddEntry(entry *HistoryEntry, now time.Time) []*VocabCard {
+	var cards []*VocabCard
+	for _, out := range entry.Outputs {
+		if strings.TrimSpace(out.Text) == "" {
+			continue
+		}
+		cards = append(cards, v.Add(entry.Input, out.Text, out.Language, entry.Kind, now))
+	}
+	return cards
+}
+
+// Delete removes the cards with the given IDs; nothing is removed if any
+// ID is unknown
+func (v *Vocab) Delete(ids ...int) error {
+	remove := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if v.card(id) == nil {
+			return fmt.Errorf("no vocabulary entry %d", id)
+		}
+		remove[id] = true
+	}
+	kept := v.Cards[:0]
+	for _, card := range v.Cards {
+		if !remove[card.ID] {
+			kept = append(kept, card)
+		}
+	}
+	v.Cards = kept
+	return nil
+}
+
+func (v *Vocab) card(id int) *VocabCard {
+	for _, card := range v.Cards {
+		if card.ID == id {
+			return card
+		}
+	}
+	return nil
+}
+
+// List returns all cards in the order they were added
+func (v *Vocab) List() VocabList {
+	return v.Cards
+}
+
+// Due returns the cards due for review at now, most overdue first
+func (v *Vocab) Due(now time.Time) VocabList {
+	var due VocabList
+	for _, card := range v.Cards {
+		if !card.Due.After(now) {
+			due = append(due, card)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].Due.Before(due[j].Due) })
+	return due
+}
+
+// Quiz reviews up to limit due cards (all if limit is 0), reading answers
+// from r. Each card shows the original text, reveals the translation on
+// Enter and is graded from 0 to 5; the notebook is saved after every grade.
+// It returns the number of cards reviewed.
+func (v *Vocab) Quiz(r io.Reader, w io.Writer, limit int) (int, error) {
+	color := shouldUseColor()
+	due := v.Due(time.Now())
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	if len(due) == 0 {
+		fmt.Fprintln(w, c("No cards due for review", colorDim, color))
+		return 0, nil
+	}
+
+	in := bufio.NewScanner(r)
+	reviewed := 0
+	for i, card := range due {
+		fmt.Fprintf(w, "\n%s %s\n", c(fmt.Sprintf("[%d/%d]", i+1, len(due)), colorGray, color),
+			c(getLanguageName(card.Language)+":", colorGreen+colorBold, color))
+		fmt.Fprintf(w, "  %s\n", card.Front)
+		fmt.Fprint(w, c("Press Enter to show the answer (q to quit) ", colorDim, color))
+		if !in.Scan() || strings.TrimSpace(in.Text()) == "q" {
+			break
+		}
+		fmt.Fprintf(w, "  %s %s\n", c("→", colorMagenta, color), strings.ReplaceAll(card.Back, "\n", "\n    "))
+
+		quality, ok := readGrade(in, w, color)
+		if !ok {
+			break
+		}
+		card.Review(quality, time.Now())
+		if err := v.Save(); err != nil {
+			return reviewed, err
+		}
+		reviewed++
+	}
+	if err := in.Err(); err != nil {
+		return reviewed, err
+	}
+	fmt.Fprintf(w, "\nReviewed %d of %d cards\n", reviewed, len(due))
+	return reviewed, nil
+}
+
+// readGrade asks for a grade until a valid one is given; ok is false on
+// quit or end of input
+func readGrade(in *bufio.Scanner, w io.Writer, color bool) (quality int, ok bool) {
+	for {
+		fmt.Fprint(w, c("Grade 0-5 (0 forgot, 3 hard, 5 easy, q to quit): ", colorDim, color))
+		if !in.Scan() {
+			return 0, false
+		}
+		answer := strings.TrimSpace(in.Text())
+		if answer == "q" {
+			return 0, false
+		}
+		if q, err := strconv.Atoi(answer); err == nil && q >= 0 && q <= 5 {
+			return q, true
+		}
+	}
+}
+
+// VocabList is a list of vocabulary cards
+type VocabList []*VocabCard
+
+// Format renders one line per card
+func (l VocabList) Format(color bool) string {
+	if len(l) == 0 {
+		return c("No vocabulary entries", colorDim, color)
+	}
+	lines := make([]string, 0, len(l))
+	for _, card := range l {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s %s %s",
+			c(fmt.Sprintf("%4d", card.ID), colorGreen+colorBold, color),
+			preview(card.Front, 30), c("→", colorMagenta, color),
+			c(card.Language+":", colorGray, color), preview(card.Back, 40),
+			c("due "+card.Due.Local().Format("2006-01-02"), colorDim, color)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteAnkiTSV writes the cards as a tab-separated file for Anki's text
+// import, with the front, the back and the target language as a tag
+func (l VocabList) WriteAnkiTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("#separator:tab\n#html:true\n#tags column:3\n")
+	for _, card := range l {
+		fmt.Fprintf(bw, "%s\t%s\t%s\n", ankiField(card.Front), ankiField(card.Back), "fanyi lang::"+card.Language)
+	}
+	return bw.Flush()
+}
+
+// ankiField escapes text for an HTML field of a tab-separated Anki file
+func ankiField(text string) string {
+	text = html.EscapeString(strings.TrimSpace(text))
+	text = strings.ReplaceAll(text, "\t", " ")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
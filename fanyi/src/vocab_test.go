@@ -0,0 +1,136 @@
+package src
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReviewSchedule(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	card := &VocabCard{Ease: initialEase, Due: now}
+
+	for i, tt := range []struct {
+		quality  int
+		interval int
+		ease     float64
+	}{
+		{4, 1, 2.5},
+		{5, 6, 2.6},
+		{3, 16, 2.46},
+		{1, 1, 1.92},
+		{4, 1, 1.92},
+	} {
+		card.Review(tt.quality, now)
+		if card.Interval != tt.interval {
+			t.Errorf("review %d: got interval %d, want %d", i, card.Interval, tt.interval)
+		}
+		if d := card.Ease - tt.ease; d > 1e-9 || d < -1e-9 {
+			t.Errorf("review %d: got ease %.4f, want %.4f", i, card.Ease, tt.ease)
+		}
+		if want := now.AddDate(0, 0, tt.interval); !card.Due.Equal(want) {
+			t.Errorf("review %d: due %v, want %v", i, card.Due, want)
+		}
+	}
+
+	for range 10 {
+		card.Review(0, now)
+	}
+	if card.Ease != minEase {
+		t.Errorf("ease dropped to %.2f", card.Ease)
+	}
+}
+
+func TestVocabAddDeleteSave(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "fanyi", vocabFile)
+	v, err := OpenVocab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cards := v.AddEntry(&HistoryEntry{Kind: HistoryLookup, Input: "serendipity", Outputs: []HistoryOutput{
+		{Language: "zh", Text: "意外发现的好运"}, {Language: "ja", Text: ""},
+	}}, now)
+	if len(cards) != 1 || cards[0].ID != 1 || cards[0].Kind != HistoryLookup {
+		t.Fatalf("got cards %+v", cards)
+	}
+	v.Add("hello", "你好", "zh", HistoryTranslation, now)
+	cards[0].Review(5, now)
+	again := v.Add("serendipity", "机缘巧合", "zh", HistoryTranslation, now.Add(time.Hour))
+	if again != cards[0] || again.Back != "机缘巧合" || again.Repetitions != 1 || len(v.Cards) != 2 {
+		t.Errorf("saving again got %+v", again)
+	}
+
+	if err := v.Delete(1, 7); err == nil || len(v.Cards) != 2 {
+		t.Error("deleted with an unknown ID")
+	}
+	if err := v.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenVocab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened.Cards) != 1 || reopened.Cards[0].Front != "hello" {
+		t.Errorf("reopened %+v", reopened.Cards)
+	}
+	if card := reopened.Add("world", "世界", "zh", HistoryTranslation, now); card.ID != 3 {
+		t.Errorf("reused ID %d", card.ID)
+	}
+}
+
+func TestVocabQuiz(t *testing.T) {
+	now := time.Now()
+	v, _ := OpenVocab(filepath.Join(t.TempDir(), vocabFile))
+	first := v.Add("good night", "晚安", "zh", HistoryTranslation, now.Add(-time.Hour))
+	second := v.Add("hello", "你好", "zh", HistoryTranslation, now.Add(-2*time.Hour))
+	later := v.Add("thanks", "谢谢", "zh", HistoryTranslation, now.AddDate(0, 0, 3))
+
+	var out bytes.Buffer
+	reviewed, err := v.Quiz(strings.NewReader("\n7\n4\n\nq\n"), &out, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewed != 1 {
+		t.Errorf("reviewed %d cards", reviewed)
+	}
+	// The most overdue card comes first and is graded after an invalid grade
+	if second.Repetitions != 1 || !second.Due.After(now) {
+		t.Errorf("hello not reviewed: %+v", second)
+	}
+	if first.Repetitions != 0 || later.Repetitions != 0 {
+		t.Error("quit did not stop the quiz")
+	}
+	if !strings.Contains(out.String(), "[1/2] Chinese:\n  hello") || !strings.Contains(out.String(), "→ 你好") {
+		t.Errorf("got quiz output\n%s", out.String())
+	}
+
+	saved, _ := OpenVocab(v.Path())
+	if saved.Cards[1].Repetitions != 1 {
+		t.Error("review was not saved")
+	}
+}
+
+func TestWriteAnkiTSV(t *testing.T) {
+	list := VocabList{
+		{Front: "a < b", Back: "1. 早上好\n2. 上午好", Language: "zh"},
+		{Front: "tab\there", Back: "タブ", Language: "ja"},
+	}
+	var out bytes.Buffer
+	if err := list.WriteAnkiTSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "#separator:tab\n#html:true\n#tags column:3\n" +
+		"a &lt; b\t1. 早上好<br>2. 上午好\tfanyi lang::zh\n" +
+		"tab here\tタブ\tfanyi lang::ja\n"
+	if out.String() != want {
+		t.Errorf("got\n%s", out.String())
+	}
+}
@@ -0,0 +1,233 @@
+package fanyi
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/monaco-io/cmd/fanyi/src"
+)
+
+// vocabSubcommands are the subcommands of fanyi vocab; without one the
+// saved cards are listed
+var vocabSubcommands = map[string]func() subcommands.Command{
+	"delete": func() subcommands.Command { return &vocabDeleteCmd{} },
+	"export": func() subcommands.Command { return &vocabExportCmd{} },
+	"quiz":   func() subcommands.Command { return &vocabQuizCmd{} },
+}
+
+type vocabCmd struct {
+	due  bool
+	json bool
+}
+
+func (*vocabCmd) Name() string     { return "vocab" }
+func (*vocabCmd) Synopsis() string { return "Review translations saved with --save" }
+func (*vocabCmd) Usage() string {
+	return `fanyi vocab [-due] [-json]
+fanyi vocab delete ID...
+fanyi vocab export [-o FILE]
+fanyi vocab quiz [-n N]
+
+Translations saved with fanyi --save are kept in vocab.json next to the
+config file (~/.config/fanyi) and scheduled for review with SM-2.
+
+`
+}
+
+func (c *vocabCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.due, "due", false, "List only the cards due for review")
+	f.BoolVar(&c.json, "json", false, "Print the cards as JSON")
+}
+
+// Subcommands returns the nested subcommands for shell completion
+func (*vocabCmd) Subcommands() []subcommands.Command {
+	names := make([]string, 0, len(vocabSubcommands))
+	for name := range vocabSubcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cmds := make([]subcommands.Command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, vocabSubcommands[name]())
+	}
+	return cmds
+}
+
+func (c *vocabCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() > 0 {
+		newCmd, ok := vocabSubcommands[f.Arg(0)]
+		if !ok {
+			fmt.Fprint(os.Stderr, c.Usage())
+			return subcommands.ExitUsageError
+		}
+		return runSubcommand(ctx, newCmd(), f.Args()[1:])
+	}
+
+	vocab, status := openVocab()
+	if vocab == nil {
+		return status
+	}
+	cards := vocab.List()
+	if c.due {
+		cards = vocab.Due(time.Now())
+	}
+	if err := printOutput(cards, c.json); err != nil {
+		fmt.Fprintf(os.Stderr, "Encoding error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type vocabDeleteCmd struct{}
+
+func (*vocabDeleteCmd) Name() string     { return "delete" }
+func (*vocabDeleteCmd) Synopsis() string { return "Delete saved cards" }
+func (*vocabDeleteCmd) Usage() string {
+	return `fanyi vocab delete ID...
+	Delete the cards with the IDs listed by fanyi vocab.
+
+`
+}
+
+func (*vocabDeleteCmd) SetFlags(*flag.FlagSet) {}
+
+func (c *vocabDeleteCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() == 0 {
+		fmt.Fprint(os.Stderr, c.Usage())
+		return subcommands.ExitUsageError
+	}
+	ids := make([]int, 0, f.NArg())
+	for _, arg := range f.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid ID %q\n", arg)
+			return subcommands.ExitUsageError
+		}
+		ids = append(ids, id)
+	}
+
+	vocab, status := openVocab()
+	if vocab == nil {
+		return status
+	}
+	if err := vocab.Delete(ids...); err != nil {
+		fmt.Fprintf(os.Stderr, "Vocabulary error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	if err := vocab.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Vocabulary error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("✓ Deleted %d card(s)\n", len(ids))
+	return subcommands.ExitSuccess
+}
+
+type vocabExportCmd struct {
+	output string
+}
+
+func (*vocabExportCmd) Name() string     { return "export" }
+func (*vocabExportCmd) Synopsis() string { return "Export the cards for Anki" }
+func (*vocabExportCmd) Usage() string {
+	return `fanyi vocab export [-o FILE]
+	Write all cards as tab-separated text for Anki (File > Import).
+
+`
+}
+
+func (c *vocabExportCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.output, "o", "", "Output file (default: stdout)")
+}
+
+func (c *vocabExportCmd) Execute(_ context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	vocab, status := openVocab()
+	if vocab == nil {
+		return status
+	}
+
+	var w io.Writer = os.Stdout
+	if c.output != "" {
+		file, err := os.Create(c.output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Export error: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := vocab.List().WriteAnkiTSV(w); err != nil {
+		fmt.Fprintf(os.Stderr, "Export error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type vocabQuizCmd struct {
+	limit int
+}
+
+func (*vocabQuizCmd) Name() string     { return "quiz" }
+func (*vocabQuizCmd) Synopsis() string { return "Review the cards that are due" }
+func (*vocabQuizCmd) Usage() string {
+	return `fanyi vocab quiz [-n N]
+	Show each due card, reveal the translation and grade your answer from
+	0 (forgot) to 5 (easy); the grade schedules the next review.
+
+`
+}
+
+func (c *vocabQuizCmd) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&c.limit, "n", 0, "Maximum number of cards to review (0 for all due)")
+}
+
+func (c *vocabQuizCmd) Execute(_ context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	vocab, status := openVocab()
+	if vocab == nil {
+		return status
+	}
+	if _, err := vocab.Quiz(os.Stdin, os.Stdout, c.limit); err != nil {
+		fmt.Fprintf(os.Stderr, "Vocabulary error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// openVocab opens the vocabulary notebook; on failure it reports the error
+// and returns the exit status
+func openVocab() (*src.Vocab, subcommands.ExitStatus) {
+	path, err := src.VocabPath()
+	if err == nil {
+		var vocab *src.Vocab
+		if vocab, err = src.OpenVocab(path); err == nil {
+			return vocab, subcommands.ExitSuccess
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Vocabulary error: %v\n", err)
+	return nil, subcommands.ExitFailure
+}
+
+// saveVocab adds the outputs of a translation to the vocabulary notebook
+func saveVocab(entry *src.HistoryEntry) subcommands.ExitStatus {
+	vocab, status := openVocab()
+	if vocab == nil {
+		return status
+	}
+	cards := vocab.AddEntry(entry, time.Now())
+	if len(cards) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to save")
+		return subcommands.ExitFailure
+	}
+	if err := vocab.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Vocabulary error: %v\n", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(os.Stderr, "✓ Saved %d card(s) to %s\n", len(cards), vocab.Path())
+	return subcommands.ExitSuccess
+}
@@ -4,6 +4,7 @@ go 1.25.0
 
 require (
 	github.com/google/subcommands v1.2.0
+	golang.org/x/net v0.47.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
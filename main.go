@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/subcommands"
 	"github.com/monaco-io/cmd/ascii_art"
+	"github.com/monaco-io/cmd/completion"
 	"github.com/monaco-io/cmd/fanyi"
 	"github.com/monaco-io/cmd/timestamp"
 )
@@ -22,6 +23,7 @@ func init() {
 		subcommands.Register(ascii_art.New(), "udf")
 		subcommands.Register(fanyi.New(), "udf")
 		subcommands.Register(timestamp.New(), "udf")
+		subcommands.Register(completion.New(), "udf")
 	}
 	{
 		subcommands.Register(fanyi.New(), "")